@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +18,7 @@ import (
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/spotahome/kooper/v2/controller/leaderelection"
+	"github.com/spotahome/kooper/v2/controller/sharding"
 	"github.com/spotahome/kooper/v2/log"
 )
 
@@ -28,17 +32,30 @@ var (
 type Controller interface {
 	// Run runs the controller and blocks until the context is `Done`.
 	Run(ctx context.Context) error
+	// Health returns an error if the controller is not healthy (e.g a worker is stuck handling an object).
+	Health(ctx context.Context) error
+	// Ready returns an error if the controller is not ready (e.g the cache is not synced or the watch
+	// is failing). A controller waiting to acquire the leadership is ready.
+	Ready(ctx context.Context) error
 }
 
 // Config is the controller configuration.
 type Config struct {
 	// Handler is the controller handler.
 	Handler Handler
+	// DeleteHandler is an optional handler that will be called with the last known state of
+	// the objects that have been deleted. If the deletion was missed (e.g watch disconnection),
+	// the last known state will be the one that the controller had in its cache.
+	DeleteHandler Handler
 	// Retriever is the controller retriever.
 	Retriever Retriever
 	// Leader elector will be used to use only one instance, if no set it will be
 	// leader election will be ignored
 	LeaderElector leaderelection.Runner
+	// Sharder is optional, if set the controller will only handle the keys that belong to the instance
+	// shard, this way multiple replicas can split the work instead of using a leader. It can't be used
+	// with a LeaderElector.
+	Sharder sharding.Sharder
 	// MetricsRecorder will record the controller metrics.
 	MetricsRecorder MetricsRecorder
 	// Logger will log messages of the controller.
@@ -52,10 +69,53 @@ type Config struct {
 	ResyncInterval time.Duration
 	// ProcessingJobRetries is the number of times the job will try to reprocess the event before returning a real error.
 	ProcessingJobRetries int
+	// HandlerTimeout is the max time that the handling of an object can take, once reached, the handler
+	// context will be cancelled and the processing will end with a `ErrHandlerTimeout` error (retried if
+	// there are retries left). If the handler ignores the context, the object will not be handled again
+	// until the hung handling returns, and when stopping, the controller will wait for the hung handlings
+	// up to the `ShutdownTimeout`. By default, there is no timeout.
+	HandlerTimeout time.Duration
+	// ShutdownTimeout is the time the controller will wait for the in-flight handlings to finish when
+	// stopping, once passed, the context of the handlers will be cancelled and the controller will stop
+	// without waiting more. By default, the controller doesn't wait and the context of the handlers is
+	// cancelled as soon as the controller stops. The abandoned handlers may still be running after the
+	// controller has stopped.
+	ShutdownTimeout time.Duration
+	// StuckHandlingThreshold is the time a worker can be handling the same object before the controller is
+	// considered not healthy (check `Health`), this way a hung handler can be detected. By default 5 minutes.
+	StuckHandlingThreshold time.Duration
+	// RateLimiter is the rate limiter that the controller queue will use on the requeues (e.g
+	// `NewFastRetryRateLimiter`), by default `NewDefaultRateLimiter`.
+	RateLimiter workqueue.TypedRateLimiter[any]
+	// NewQueue is an optional custom queue constructor (e.g returning a `NewRateLimitingQueue`). If set, `RateLimiter`
+	// will be ignored and the retries limit (check `ProcessingJobRetries`) should be handled by the queue. A queue
+	// can't be used once it has been shut down, so it will be called every time the controller runs.
+	NewQueue func() Queue
 	// DisableResync will disable resyncing, if disabled the controller only will react on event updates and resync
 	// all when it runs for the first time.
 	// This is useful for secondary resource controllers (e.g pod controller of a primary controller based on deployments).
 	DisableResync bool
+	// Predicates are optional filters of the resource events, only the events allowed by all the predicates
+	// will be queued (e.g `PredicateGenerationChanged`). Take into account that the resyncs are update events.
+	Predicates []Predicate
+	// ObjectTransform is an optional transform that will be applied to the objects before being stored on the
+	// controller cache, useful to reduce the memory removing the fields not used by the handlers (e.g
+	// `TransformStripManagedFields`). When using SharedInformers, the transform of the controller that
+	// creates the informer will be used.
+	ObjectTransform cache.TransformFunc
+	// Watches are the optional secondary resources that the controller will watch, their events will
+	// be mapped to the primary resource (the one of the Retriever) keys and handled by the Handler.
+	Watches []Watch
+	// Indexers are custom indexers that will be added to the controller local cache, these can be used by
+	// the handlers to get objects from the cache by index using the `Lister` (check `ListerFromContext`).
+	Indexers cache.Indexers
+	// SharedInformers is optional, if set the controller informer will be shared with the other controllers
+	// that use the same SharedInformers and SharedInformerKey, instead of having its own informer.
+	SharedInformers *SharedInformers
+	// SharedInformerKey is the key that identifies the informer on the SharedInformers (e.g the resource GVK
+	// `apps/v1/Deployment`). All the controllers that use the same key must use an equivalent Retriever, the
+	// informer will be created with the Retriever of the first controller.
+	SharedInformerKey string
 }
 
 func (c *Config) setDefaults() error {
@@ -71,6 +131,20 @@ func (c *Config) setDefaults() error {
 		return fmt.Errorf("a retriever is required")
 	}
 
+	for i, w := range c.Watches {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("invalid watch %d: %w", i, err)
+		}
+	}
+
+	if c.Sharder != nil && c.LeaderElector != nil {
+		return fmt.Errorf("sharding and leader election can't be used at the same time")
+	}
+
+	if c.SharedInformers != nil && c.SharedInformerKey == "" {
+		return fmt.Errorf("a shared informer key is required when using shared informers")
+	}
+
 	if c.Logger == nil {
 		c.Logger = log.NewStd(false)
 		c.Logger.Warningf("no logger specified, fallback to default logger, to disable logging use a explicit Noop logger")
@@ -101,16 +175,60 @@ func (c *Config) setDefaults() error {
 		c.ProcessingJobRetries = 0
 	}
 
+	if c.HandlerTimeout < 0 {
+		c.HandlerTimeout = 0
+	}
+
+	if c.ShutdownTimeout < 0 {
+		c.ShutdownTimeout = 0
+	}
+
+	if c.StuckHandlingThreshold <= 0 {
+		c.StuckHandlingThreshold = 5 * time.Minute
+	}
+
+	if c.RateLimiter == nil {
+		c.RateLimiter = NewDefaultRateLimiter()
+	}
+
 	return nil
 }
 
+// watchInformer is the informer of a secondary resource with our event handler registration.
+type watchInformer struct {
+	informer   *refCountedInformer
+	handlerReg cache.ResourceEventHandlerRegistration
+}
+
+// runState is the state of a controller run. The queue and the informers can't be reused once they
+// have been stopped, so every time the controller runs (e.g re-entering the leader election) it needs
+// a new state.
+type runState struct {
+	queue      Queue                                  // queue will have the jobs that the controller will get and send to handlers.
+	informer   *refCountedInformer                    // informer will notify be inform us about resource changes.
+	handlerReg cache.ResourceEventHandlerRegistration // handlerReg is our event handler registration on the informer.
+	processor  processor                              // processor will call the user handler (logic).
+	timeouts   *timeoutProcessor                      // timeouts has the processings abandoned by the handler timeout, nil if there is no timeout.
+	deleted    *deletedObjectStore                    // deleted will have the last state of deleted objects, nil if not handling deletes.
+	watches    []watchInformer                        // watches are the secondary resources informers.
+
+	handlingsMu sync.Mutex
+	handlings   []handling // handlings are the objects being handled by each worker.
+}
+
+// handling is the object being handled by a worker, the key is empty if the worker is idle.
+type handling struct {
+	key       string
+	startedAt time.Time
+}
+
 // generic controller is a controller that can be used to create different kind of controllers.
 type generic struct {
-	queue     blockingQueue             // queue will have the jobs that the controller will get and send to handlers.
-	informer  cache.SharedIndexInformer // informer will notify be inform us about resource changes.
-	processor processor                 // processor will call the user handler (logic).
-
-	running   bool
+	state     *runState // state is the state of the current (or next) run.
+	stateUsed bool      // stateUsed is true once the state has been used by a run and can't be used again.
+	started   bool      // started is true while the controller Run is running (e.g waiting for the leadership).
+	running   bool      // running is true while the controller is running (e.g leading).
+	synced    bool      // synced is true once the controller caches have been synced.
 	runningMu sync.Mutex
 	cfg       Config
 	metrics   MetricsRecorder
@@ -138,46 +256,121 @@ func New(cfg *Config) (Controller, error) {
 		return nil, fmt.Errorf("could no create controller: %w: %v", ErrControllerNotValid, err)
 	}
 
+	g := &generic{
+		metrics:  cfg.MetricsRecorder,
+		leRunner: cfg.LeaderElector,
+		cfg:      *cfg,
+		logger:   cfg.Logger,
+	}
+
+	// Set up the state of the first run, this way any problem is returned on the creation.
+	g.state, err = g.newRunState()
+	if err != nil {
+		return nil, err
+	}
+
+	// Register func/callback based metrics. These are controlled by the MetricsRecorder. The queue
+	// changes on every run, so we measure the current one.
+	err = cfg.MetricsRecorder.RegisterResourceQueueLengthFunc(cfg.Name, g.queueLen)
+	if err != nil {
+		return nil, fmt.Errorf("could not measure the queue: %w", err)
+	}
+
+	return g, nil
+}
+
+// newRunState creates the queue, the informers and the processing chain used by a controller run.
+func (g *generic) newRunState() (*runState, error) {
+	cfg := g.cfg
+
 	// Create the queue that will have our received job changes.
-	queue := newRateLimitingBlockingQueue(
-		cfg.ProcessingJobRetries,
-		workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any]()),
-	)
+	var queue Queue
+	if cfg.NewQueue != nil {
+		queue = cfg.NewQueue()
+	} else {
+		queue = NewRateLimitingQueue(cfg.ProcessingJobRetries, cfg.RateLimiter)
+	}
 
 	// Measure the queue.
-	queue, err = newMetricsBlockingQueue(
+	queue = newMetricsBlockingQueue(
 		cfg.Name,
 		cfg.MetricsRecorder,
 		queue,
 		cfg.Logger,
 	)
-	if err != nil {
-		return nil, fmt.Errorf("could not measure the queue: %w", err)
+
+	// If we handle deletes, we need to store the last known state of the deleted objects.
+	var deleted *deletedObjectStore
+	if cfg.DeleteHandler != nil {
+		deleted = newDeletedObjectStore()
+	}
+
+	// The informer has the internal cache where objects will be stored, it can be shared
+	// with other controllers.
+	var (
+		informer *refCountedInformer
+		err      error
+	)
+	if cfg.SharedInformers != nil {
+		informer, err = cfg.SharedInformers.informer(cfg.SharedInformerKey, cfg.Retriever, cfg.ResyncInterval, cfg.ObjectTransform)
+		if err != nil {
+			return nil, fmt.Errorf("could not create shared informer: %w", err)
+		}
+	} else {
+		informer, err = newInformer(cfg.Retriever, cfg.ResyncInterval, cfg.ObjectTransform)
+		if err != nil {
+			return nil, fmt.Errorf("could not create informer: %w", err)
+		}
 	}
 
-	// store is the internal cache where objects will be store.
-	store := cache.Indexers{}
-	lw := listerWatcherFromRetriever(cfg.Retriever)
-	informer := cache.NewSharedIndexInformer(lw, nil, cfg.ResyncInterval, store)
+	if len(cfg.Indexers) > 0 {
+		err := informer.addIndexers(cfg.Indexers)
+		if err != nil {
+			return nil, fmt.Errorf("could not add indexers on controller: %w", err)
+		}
+	}
 
 	// Set up our informer event handler.
 	// Objects are already in our local store. Add only keys/jobs on the queue so they can re processed
 	// afterwards.
-	_, err = informer.AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+	// Only the events of the keys owned by the shard and allowed by the predicates will be queued.
+	owned := func(key string) bool {
+		return cfg.Sharder == nil || cfg.Sharder.Owns(key)
+	}
+	allowed := func(ev Event) bool {
+		if allowEvent(cfg.Predicates, ev) {
+			return true
+		}
+		cfg.MetricsRecorder.IncResourceEventFiltered(context.TODO(), cfg.Name)
+		return false
+	}
+	handlerReg, err := informer.informer.AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			key, err := cache.MetaNamespaceKeyFunc(obj)
 			if err != nil {
 				cfg.Logger.Warningf("could not add item from 'add' event to queue: %s", err)
 				return
 			}
+			if deleted != nil {
+				deleted.Delete(key)
+			}
+			robj, _ := obj.(runtime.Object)
+			if !owned(key) || !allowed(Event{Type: EventAdd, Object: robj}) {
+				return
+			}
 			queue.Add(context.TODO(), key)
 		},
-		UpdateFunc: func(_ interface{}, new interface{}) {
+		UpdateFunc: func(old interface{}, new interface{}) {
 			key, err := cache.MetaNamespaceKeyFunc(new)
 			if err != nil {
 				cfg.Logger.Warningf("could not add item from 'update' event to queue: %s", err)
 				return
 			}
+			robj, _ := new.(runtime.Object)
+			oldRobj, _ := old.(runtime.Object)
+			if !owned(key) || !allowed(Event{Type: EventUpdate, Object: robj, OldObject: oldRobj}) {
+				return
+			}
 			queue.Add(context.TODO(), key)
 		},
 		DeleteFunc: func(obj interface{}) {
@@ -186,6 +379,17 @@ func New(cfg *Config) (Controller, error) {
 				cfg.Logger.Warningf("could not add item from 'delete' event to queue: %s", err)
 				return
 			}
+			// If we missed the deletion, we receive the last known state inside a tombstone.
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			robj, _ := obj.(runtime.Object)
+			if !owned(key) || !allowed(Event{Type: EventDelete, Object: robj}) {
+				return
+			}
+			if deleted != nil && robj != nil {
+				deleted.Set(key, robj)
+			}
 			queue.Add(context.TODO(), key)
 		},
 	}, cfg.ResyncInterval)
@@ -193,22 +397,45 @@ func New(cfg *Config) (Controller, error) {
 		return nil, fmt.Errorf("could not set event handler on controller: %w", err)
 	}
 
+	// Set up the secondary resources informers, these will only add the mapped primary keys on the queue.
+	watches := make([]watchInformer, 0, len(cfg.Watches))
+	for _, w := range cfg.Watches {
+		// Secondary resources don't need resync, the primary resource resync will handle them.
+		informer, err := newInformer(w.Retriever, 0, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not create controller watch informer: %w", err)
+		}
+		reg, err := informer.informer.AddEventHandler(newWatchEventHandler(w.Mapper, queue, cfg.Logger))
+		if err != nil {
+			return nil, fmt.Errorf("could not set event handler on controller watch: %w", err)
+		}
+		watches = append(watches, watchInformer{informer: informer, handlerReg: reg})
+	}
+
 	// Create processing chain: processor(+middlewares) -> handler(+middlewares).
-	processor := newIndexerProcessor(informer.GetIndexer(), cfg.Handler)
+	processor := newIndexerProcessor(informer.informer.GetIndexer(), deleted, cfg.Handler, cfg.DeleteHandler)
+	// Recover must be wrapped by the timeout processor, the timeout processor runs the processing on a different goroutine.
+	processor = newRecoverProcessor(cfg.Name, cfg.Logger, cfg.MetricsRecorder, processor)
+	var timeouts *timeoutProcessor
+	if cfg.HandlerTimeout > 0 {
+		timeouts = newTimeoutProcessor(cfg.Name, cfg.HandlerTimeout, cfg.MetricsRecorder, processor)
+		processor = timeouts
+	}
 	if cfg.ProcessingJobRetries > 0 {
 		processor = newRetryProcessor(cfg.Name, queue, cfg.Logger, processor)
 	}
+	processor = newRequeueProcessor(queue, cfg.Logger, processor)
 	processor = newMetricsProcessor(cfg.Name, cfg.MetricsRecorder, processor)
 
-	// Create our generic controller object.
-	return &generic{
-		queue:     queue,
-		informer:  informer,
-		metrics:   cfg.MetricsRecorder,
-		processor: processor,
-		leRunner:  cfg.LeaderElector,
-		cfg:       *cfg,
-		logger:    cfg.Logger,
+	return &runState{
+		queue:      queue,
+		informer:   informer,
+		handlerReg: handlerReg,
+		processor:  processor,
+		timeouts:   timeouts,
+		deleted:    deleted,
+		watches:    watches,
+		handlings:  make([]handling, cfg.ConcurrentWorkers),
 	}, nil
 }
 
@@ -224,13 +451,189 @@ func (g *generic) setRunning(running bool) {
 	g.running = running
 }
 
+func (g *generic) setStarted(started bool) {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+	g.started = started
+}
+
+func (g *generic) setSynced(synced bool) {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+	g.synced = synced
+}
+
+// nextRunState returns the state that will be used by a run with its informers acquired (running), if
+// the current state has already been used (or its shared informer has been stopped by other controllers)
+// a new one is created.
+func (g *generic) nextRunState() (*runState, error) {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+
+	for {
+		if g.stateUsed {
+			st, err := g.newRunState()
+			if err != nil {
+				return nil, err
+			}
+			g.state = st
+		}
+		g.stateUsed = true
+
+		if g.state.acquire() {
+			return g.state, nil
+		}
+
+		// The stopped informer will not notify us anymore.
+		err := g.state.informer.informer.RemoveEventHandler(g.state.handlerReg)
+		if err != nil {
+			g.logger.Warningf("could not remove event handler from informer: %s", err)
+		}
+	}
+}
+
+// queueLen returns the length of the current run queue.
+func (g *generic) queueLen(ctx context.Context) int {
+	g.runningMu.Lock()
+	st := g.state
+	g.runningMu.Unlock()
+
+	return st.queue.Len(ctx)
+}
+
+// acquire acquires (runs) the state informers, it returns false if any of them has been stopped.
+func (s *runState) acquire() bool {
+	if !s.informer.acquire() {
+		return false
+	}
+
+	for i, w := range s.watches {
+		if !w.informer.acquire() {
+			for _, w := range s.watches[:i] {
+				w.informer.release()
+			}
+			s.informer.release()
+			return false
+		}
+	}
+
+	return true
+}
+
+// release releases the state informers, the informers will stop if nobody else is using them.
+func (s *runState) release() {
+	for _, w := range s.watches {
+		w.informer.release()
+	}
+	s.informer.release()
+}
+
+func (s *runState) setHandling(worker int, h handling) {
+	s.handlingsMu.Lock()
+	defer s.handlingsMu.Unlock()
+	s.handlings[worker] = h
+}
+
+// stuckHandling returns the worker that has been handling the same object for more than the threshold (if any).
+func (s *runState) stuckHandling(threshold time.Duration) (worker int, d time.Duration, stuck bool) {
+	s.handlingsMu.Lock()
+	defer s.handlingsMu.Unlock()
+
+	for worker, h := range s.handlings {
+		if h.key == "" {
+			continue
+		}
+		if d := time.Since(h.startedAt); d > threshold {
+			return worker, d, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// handlingKeys returns the keys of the objects that are being handled, including the ones whose
+// handling has been abandoned by the handler timeout and is still running.
+func (s *runState) handlingKeys() []string {
+	keys := map[string]struct{}{}
+
+	s.handlingsMu.Lock()
+	for _, h := range s.handlings {
+		if h.key != "" {
+			keys[h.key] = struct{}{}
+		}
+	}
+	s.handlingsMu.Unlock()
+
+	if s.timeouts != nil {
+		for _, key := range s.timeouts.processingKeys() {
+			keys[key] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(keys))
+}
+
+// watchError returns the error of the failing informers list and watch (if any).
+func (s *runState) watchError() error {
+	if err := s.informer.health.error(); err != nil {
+		return err
+	}
+
+	for _, w := range s.watches {
+		if err := w.informer.health.error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Health satisfies controller.Controller interface.
+func (g *generic) Health(_ context.Context) error {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+
+	if !g.running || !g.synced {
+		return nil
+	}
+
+	if worker, d, stuck := g.state.stuckHandling(g.cfg.StuckHandlingThreshold); stuck {
+		return fmt.Errorf("%w: controller %q worker %d has been handling the same object for %s", ErrNotHealthy, g.cfg.Name, worker, d.Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+// Ready satisfies controller.Controller interface.
+func (g *generic) Ready(_ context.Context) error {
+	g.runningMu.Lock()
+	defer g.runningMu.Unlock()
+
+	switch {
+	case !g.started:
+		return fmt.Errorf("%w: controller %q is not running", ErrNotReady, g.cfg.Name)
+	case !g.running:
+		// Waiting for the leadership.
+		return nil
+	case !g.synced:
+		return fmt.Errorf("%w: controller %q caches are not synced", ErrNotReady, g.cfg.Name)
+	}
+
+	if err := g.state.watchError(); err != nil {
+		return fmt.Errorf("%w: controller %q watch is failing: %w", ErrNotReady, g.cfg.Name, err)
+	}
+
+	return nil
+}
+
 // Run will run the controller.
 func (g *generic) Run(ctx context.Context) error {
+	g.setStarted(true)
+	defer g.setStarted(false)
+
 	// Check if leader election is required.
 	if g.leRunner != nil {
-		return g.leRunner.Run(func() error {
-			return g.run(ctx)
-		})
+		return g.leRunner.Run(ctx, g.run)
 	}
 
 	return g.run(ctx)
@@ -242,6 +645,13 @@ func (g *generic) run(ctx context.Context) error {
 		return fmt.Errorf("controller already running")
 	}
 
+	// The queue and the informers are stopped at the end of the run, so each run needs its own. The
+	// informers start running (if they are shared, they could be already running).
+	st, err := g.nextRunState()
+	if err != nil {
+		return fmt.Errorf("could not set up the controller run: %w", err)
+	}
+
 	g.logger.Infof("starting controller")
 	// Set state of controller.
 	g.setRunning(true)
@@ -249,21 +659,62 @@ func (g *generic) run(ctx context.Context) error {
 
 	// Shutdown when Run is stopped so we can process the last items and the queue doesn't
 	// accept more jobs.
-	defer g.queue.ShutDown(ctx)
+	defer st.queue.ShutDown(ctx)
+
+	// Stop receiving events from the informer once we stop.
+	defer func() {
+		err := st.informer.informer.RemoveEventHandler(st.handlerReg)
+		if err != nil {
+			g.logger.Warningf("could not remove event handler from informer: %s", err)
+		}
+	}()
+
+	// Stop the informers once we stop (if they are shared, only when nobody else is using them).
+	defer st.release()
+
+	hasSynced := []cache.InformerSynced{st.handlerReg.HasSynced}
+	for _, w := range st.watches {
+		hasSynced = append(hasSynced, w.handlerReg.HasSynced)
+	}
+
+	// Run the sharder so we know the keys owned by the instance, if it fails the controller will stop.
+	var sharderErrC chan error
+	if g.cfg.Sharder != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
 
-	// Run the informer so it starts listening to resource events.
-	go g.informer.Run(ctx.Done())
+		sharderErrC = make(chan error, 1)
+		go func() {
+			err := g.cfg.Sharder.Run(ctx, func(gained func(key string) bool) { g.enqueueGained(st, gained) })
+			if err != nil {
+				g.logger.Errorf("sharder failed, stopping controller: %s", err)
+				cancel()
+			}
+			sharderErrC <- err
+		}()
+	}
 
 	// Wait until our store, jobs... stuff is synced (first list on resource, resources on store and jobs on queue).
-	if !cache.WaitForCacheSync(ctx.Done(), g.informer.HasSynced) {
+	if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
 		return fmt.Errorf("timed out waiting for caches to sync")
 	}
+	g.setSynced(true)
+	defer g.setSynced(false)
+
+	// The handlers context is not cancelled when the controller stops, so in-flight handlings can finish
+	// gracefully, it will be cancelled after the shutdown timeout.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
 
 	// Start our resource processing worker, if finishes then restart the worker. The workers should
 	// not end.
+	var wg sync.WaitGroup
 	for i := 0; i < g.cfg.ConcurrentWorkers; i++ {
+		wg.Add(1)
 		go func() {
-			wait.Until(g.runWorker, time.Second, ctx.Done())
+			defer wg.Done()
+			wait.Until(func() { g.runWorker(ctx, handlerCtx, st, i) }, time.Second, ctx.Done())
 		}()
 	}
 
@@ -272,14 +723,83 @@ func (g *generic) run(ctx context.Context) error {
 	<-ctx.Done()
 	g.logger.Infof("stopping controller")
 
+	// Stop accepting new jobs and wait for the in-flight ones.
+	st.queue.ShutDown(handlerCtx)
+	g.waitWorkers(st, &wg, cancelHandlers)
+
+	// Wait until the sharder leaves the sharding group.
+	if sharderErrC != nil {
+		if err := <-sharderErrC; err != nil {
+			return fmt.Errorf("sharder failed: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// enqueueGained adds the keys gained by the instance shard to the queue, called when the
+// shards are rebalanced so the instance handles the keys it has received.
+func (g *generic) enqueueGained(st *runState, gained func(key string) bool) {
+	for _, key := range st.informer.informer.GetIndexer().ListKeys() {
+		if gained(key) {
+			st.queue.Add(context.TODO(), key)
+		}
+	}
+}
+
+// waitWorkers will wait for the workers to end their in-flight jobs (and for the processings abandoned
+// by the handler timeout) up to the shutdown timeout, after that (or if there is no shutdown timeout)
+// the handlers context will be cancelled and the in-flight jobs will be abandoned without waiting more,
+// this way a handler that ignores the context can't block the controller stop.
+func (g *generic) waitWorkers(st *runState, wg *sync.WaitGroup, cancelHandlers func()) {
+	if g.cfg.ShutdownTimeout > 0 {
+		doneC := make(chan struct{})
+		go func() {
+			wg.Wait()
+			// Once the workers have ended, there will not be new processings.
+			if st.timeouts != nil {
+				st.timeouts.wait()
+			}
+			close(doneC)
+		}()
+
+		select {
+		case <-doneC:
+			g.logger.Infof("in-flight jobs finished")
+			return
+		case <-time.After(g.cfg.ShutdownTimeout):
+			g.logger.Warningf("shutdown timeout reached, cancelling in-flight jobs")
+		}
+	}
+
+	cancelHandlers()
+	if keys := st.handlingKeys(); len(keys) > 0 {
+		g.logger.Warningf("abandoning in-flight jobs, their handlers may still be running: %s", strings.Join(keys, ", "))
+	}
+}
+
+// done marks the job as done on the queue. If the job processing has been abandoned by the handler
+// timeout and is still running, the job will be held until it ends, this way the queue doesn't
+// give the same key to another worker while the previous processing is running.
+func (g *generic) done(ctx context.Context, st *runState, job interface{}) {
+	if st.timeouts != nil {
+		if runningC := st.timeouts.running(job.(string)); runningC != nil {
+			go func() {
+				<-runningC
+				st.queue.Done(ctx, job)
+			}()
+			return
+		}
+	}
+
+	st.queue.Done(ctx, job)
+}
+
 // runWorker will start a processing loop on event queue.
-func (g *generic) runWorker() {
+func (g *generic) runWorker(ctx, handlerCtx context.Context, st *runState, worker int) {
 	for {
 		// Process next queue job, if needs to stop processing it will return true.
-		if g.processNextJob() {
+		if g.processNextJob(ctx, handlerCtx, st, worker) {
 			break
 		}
 	}
@@ -288,21 +808,34 @@ func (g *generic) runWorker() {
 // processNextJob job will process the next job of the queue job and returns if
 // it needs to stop processing.
 //
-// If the queue has been closed then it will end the processing.
-func (g *generic) processNextJob() bool {
-	ctx := context.Background()
-
+// If the queue has been closed or the controller is stopping, then it will end the processing.
+// The jobs are processed using the handler context.
+func (g *generic) processNextJob(ctx, handlerCtx context.Context, st *runState, worker int) bool {
 	// Get next job.
-	nextJob, exit := g.queue.Get(ctx)
+	nextJob, exit := st.queue.Get(handlerCtx)
 	if exit {
 		return true
 	}
+	defer g.done(handlerCtx, st, nextJob)
+
+	// If we are stopping, don't process the pending jobs.
+	if ctx.Err() != nil {
+		return true
+	}
 
-	defer g.queue.Done(ctx, nextJob)
 	key := nextJob.(string)
 
-	// Process the job.
-	err := g.processor.Process(ctx, key)
+	// The key could have moved to another shard while it was queued.
+	if g.cfg.Sharder != nil && !g.cfg.Sharder.Owns(key) {
+		g.logger.WithKV(log.KV{"object-key": key}).Debugf("object not owned by the shard, ignoring")
+		st.queue.Forget(handlerCtx, key)
+		return false
+	}
+
+	// Process the job, tracking the handling so a stuck worker can be detected.
+	st.setHandling(worker, handling{key: key, startedAt: time.Now()})
+	err := st.processor.Process(handlerCtx, key)
+	st.setHandling(worker, handling{})
 
 	logger := g.logger.WithKV(log.KV{"object-key": key})
 	switch {
@@ -310,9 +843,17 @@ func (g *generic) processNextJob() bool {
 		logger.Debugf("object processed")
 	case errors.Is(err, errRequeued):
 		logger.Warningf("error on object processing, retrying: %v", err)
+	case IsPermanent(err):
+		logger.Errorf("permanent error on object processing, not retrying: %v", err)
 	default:
 		logger.Errorf("error on object processing: %v", err)
 	}
 
+	// If the processing ended with an error that will not be retried, we don't need the deleted
+	// object anymore.
+	if err != nil && !errors.Is(err, errRequeued) && st.deleted != nil {
+		st.deleted.Delete(key)
+	}
+
 	return false
 }
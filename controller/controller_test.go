@@ -3,7 +3,9 @@ package controller_test
 import (
 	"context"
 	"fmt"
+	"maps"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,12 +14,14 @@ import (
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
 
 	"github.com/spotahome/kooper/v2/controller"
 	"github.com/spotahome/kooper/v2/controller/controllermock"
@@ -309,7 +313,10 @@ func TestGenericControllerWithLeaderElection(t *testing.T) {
 			// Run multiple controller in background.
 			go func() { resultC <- c1.Run(ctx) }()
 			// Let the first controller became the leader.
-			time.Sleep(200 * time.Microsecond)
+			require.Eventually(func() bool {
+				lease, err := mc.CoordinationV1().Leases("default").Get(context.Background(), "test", metav1.GetOptions{})
+				return err == nil && lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != ""
+			}, time.Second, time.Millisecond)
 			go func() { resultC <- c2.Run(ctx) }()
 			go func() { resultC <- c3.Run(ctx) }()
 
@@ -328,3 +335,1401 @@ func TestGenericControllerWithLeaderElection(t *testing.T) {
 		})
 	}
 }
+
+func TestGenericControllerHandlerResults(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		retryNumber  int
+		handleResult func(call int) error
+		expCalls     int
+	}{
+		"Requeuing after a duration should handle the object again, without using the retries.": {
+			retryNumber: 0,
+			handleResult: func(call int) error {
+				if call == 1 {
+					return controller.RequeueAfter(10 * time.Millisecond)
+				}
+				return nil
+			},
+			expCalls: 2,
+		},
+
+		"Requeuing should handle the object again, without using the retries.": {
+			retryNumber: 0,
+			handleResult: func(call int) error {
+				if call < 3 {
+					return controller.Requeue()
+				}
+				return nil
+			},
+			expCalls: 3,
+		},
+
+		"Requeuing before failing should not use the retries of the error.": {
+			retryNumber: 2,
+			handleResult: func(call int) error {
+				if call <= 3 {
+					return controller.Requeue()
+				}
+				return fmt.Errorf("wanted error")
+			},
+			expCalls: 6,
+		},
+
+		"Failing without retries after requeuing should reset the requeues.": {
+			retryNumber: 0,
+			handleResult: func(call int) error {
+				if call == 1 {
+					return controller.Requeue()
+				}
+				return fmt.Errorf("wanted error")
+			},
+			expCalls: 2,
+		},
+
+		"Permanent errors should not be retried.": {
+			retryNumber: 3,
+			handleResult: func(call int) error {
+				return controller.Permanent(fmt.Errorf("wanted error"))
+			},
+			expCalls: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := &fake.Clientset{}
+			onKubeClientListNamespaceReturn(mc, nsList)
+
+			// Mock our handler and set expects.
+			var mu sync.Mutex
+			calls := map[string]int{}
+			totalCalls := len(nsList.Items) * test.expCalls
+			mh := &controllermock.Handler{}
+			mh.On("Handle", mock.Anything, mock.Anything).Times(totalCalls).Return(func(_ context.Context, obj runtime.Object) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				ns := obj.(*corev1.Namespace)
+				calls[ns.Name]++
+				totalCalls--
+				// Check last call, if is the last call expected then stop the controller so
+				// we can assert the expectations of the calls and finish the test.
+				if totalCalls <= 0 {
+					cancelCtx()
+				}
+				return test.handleResult(calls[ns.Name])
+			})
+
+			rateLimiter := workqueue.NewTypedItemExponentialFailureRateLimiter[any](time.Millisecond, time.Second)
+			c, err := controller.New(&controller.Config{
+				Name:                 "test",
+				Handler:              mh,
+				Retriever:            newNamespaceRetriever(mc),
+				ProcessingJobRetries: test.retryNumber,
+				RateLimiter:          rateLimiter,
+				ShutdownTimeout:      time.Second,
+				Logger:               log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			// Wait for different results. If no result means error failure.
+			select {
+			case err := <-resultC:
+				if assert.NoError(err) {
+					mh.AssertExpectations(t)
+					mu.Lock()
+					for _, ns := range nsList.Items {
+						assert.Equal(test.expCalls, calls[ns.Name])
+						// Once handled without more requeues, the rate limiter should forget the object.
+						assert.Zero(rateLimiter.NumRequeues(ns.Name))
+					}
+					mu.Unlock()
+				}
+			case <-time.After(1 * time.Second):
+				assert.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+		})
+	}
+}
+
+func TestGenericControllerDeleteHandler(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		deleteNS []string
+	}{
+		"Deleting objects should call the delete handler with the last known state of the deleted objects.": {
+			deleteNS: []string{"testing-1", "testing-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			// Delete the namespaces once all have been handled.
+			var mu sync.Mutex
+			handled := 0
+			mh := &controllermock.Handler{}
+			mh.On("Handle", mock.Anything, mock.Anything).Times(len(nsList.Items)).Return(nil).Run(func(args mock.Arguments) {
+				mu.Lock()
+				defer mu.Unlock()
+				handled++
+				if handled == len(nsList.Items) {
+					go func() {
+						for _, ns := range test.deleteNS {
+							err := mc.CoreV1().Namespaces().Delete(context.TODO(), ns, metav1.DeleteOptions{})
+							assert.NoError(err)
+						}
+					}()
+				}
+			})
+
+			deleted := 0
+			mdh := &controllermock.Handler{}
+			for _, ns := range test.deleteNS {
+				expNS := mock.MatchedBy(func(obj runtime.Object) bool {
+					n, ok := obj.(*corev1.Namespace)
+					return ok && n.Name == ns
+				})
+				mdh.On("Handle", mock.Anything, expNS).Once().Return(nil).Run(func(args mock.Arguments) {
+					mu.Lock()
+					defer mu.Unlock()
+					deleted++
+					if deleted == len(test.deleteNS) {
+						cancelCtx()
+					}
+				})
+			}
+
+			c, err := controller.New(&controller.Config{
+				Name:          "test",
+				Handler:       mh,
+				DeleteHandler: mdh,
+				Retriever:     newNamespaceRetriever(mc),
+				Logger:        log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			// Wait for different results. If no result means error failure.
+			select {
+			case err := <-resultC:
+				if assert.NoError(err) {
+					mh.AssertExpectations(t)
+					mdh.AssertExpectations(t)
+				}
+			case <-time.After(1 * time.Second):
+				assert.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+		})
+	}
+}
+
+func TestGenericControllerSharedInformers(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		controllers int
+		expLists    int
+	}{
+		"Multiple controllers using the same shared informer should list the resources only once.": {
+			controllers: 3,
+			expLists:    1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+
+			// Mocks kubernetes  client.
+			var mu sync.Mutex
+			lists := 0
+			mc := &fake.Clientset{}
+			mc.AddReactor("list", "namespaces", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				lists++
+				return true, nsList, nil
+			})
+			mc.AddWatchReactor("namespaces", func(action kubetesting.Action) (bool, watch.Interface, error) {
+				return true, watch.NewFake(), nil
+			})
+
+			// Every controller should handle all the namespaces.
+			totalCalls := len(nsList.Items) * test.controllers
+			mh := &controllermock.Handler{}
+			mh.On("Handle", mock.Anything, mock.Anything).Times(totalCalls).Return(nil).Run(func(args mock.Arguments) {
+				mu.Lock()
+				defer mu.Unlock()
+				totalCalls--
+				if totalCalls <= 0 {
+					cancelCtx()
+				}
+			})
+
+			sharedInformers := controller.NewSharedInformers()
+			ret := newNamespaceRetriever(mc)
+			resultC := make(chan error, test.controllers)
+			for i := 0; i < test.controllers; i++ {
+				c, err := controller.New(&controller.Config{
+					Name:              fmt.Sprintf("test-%d", i),
+					Handler:           mh,
+					Retriever:         ret,
+					SharedInformers:   sharedInformers,
+					SharedInformerKey: "v1/Namespace",
+					Logger:            log.Dummy,
+				})
+				require.NoError(err)
+
+				go func() {
+					resultC <- c.Run(ctx)
+				}()
+			}
+
+			// Wait for all the controllers.
+			for i := 0; i < test.controllers; i++ {
+				select {
+				case err := <-resultC:
+					assert.NoError(err)
+				case <-time.After(1 * time.Second):
+					assert.FailNow("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+				}
+			}
+
+			mh.AssertExpectations(t)
+			mu.Lock()
+			assert.Equal(test.expLists, lists)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestGenericControllerSharedInformersStopped(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		expLists int
+	}{
+		"A controller should use a new shared informer when the informer has been stopped by the other controllers.": {
+			expLists: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			// Mocks kubernetes  client.
+			var mu sync.Mutex
+			lists := 0
+			mc := &fake.Clientset{}
+			mc.AddReactor("list", "namespaces", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				lists++
+				return true, nsList, nil
+			})
+			mc.AddWatchReactor("namespaces", func(action kubetesting.Action) (bool, watch.Interface, error) {
+				return true, watch.NewFake(), nil
+			})
+
+			// Both controllers are created while the informer is not stopped.
+			sharedInformers := controller.NewSharedInformers()
+			ret := newNamespaceRetriever(mc)
+			newController := func(name string, cancel func()) controller.Controller {
+				var mu sync.Mutex
+				calls := 0
+				h := controller.HandlerFunc(func(context.Context, runtime.Object) error {
+					mu.Lock()
+					defer mu.Unlock()
+					calls++
+					if calls == len(nsList.Items) {
+						cancel()
+					}
+					return nil
+				})
+				c, err := controller.New(&controller.Config{
+					Name:              name,
+					Handler:           h,
+					Retriever:         ret,
+					SharedInformers:   sharedInformers,
+					SharedInformerKey: "v1/Namespace",
+					Logger:            log.Dummy,
+				})
+				require.NoError(err)
+				return c
+			}
+			ctx1, cancel1 := context.WithCancel(context.Background())
+			defer cancel1()
+			ctx2, cancel2 := context.WithCancel(context.Background())
+			defer cancel2()
+			c1 := newController("test-1", cancel1)
+			c2 := newController("test-2", cancel2)
+
+			// Run the controllers one after the other, the second one should handle all the objects
+			// although the first one stopped the informer.
+			for _, run := range []func() error{
+				func() error { return c1.Run(ctx1) },
+				func() error { return c2.Run(ctx2) },
+			} {
+				resultC := make(chan error, 1)
+				go func() { resultC <- run() }()
+				select {
+				case err := <-resultC:
+					assert.NoError(err)
+				case <-time.After(1 * time.Second):
+					assert.FailNow("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+				}
+			}
+
+			mu.Lock()
+			assert.Equal(test.expLists, lists)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestGenericControllerLister(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 6)
+	for i := range nsList.Items {
+		team := "team-a"
+		if i%2 == 0 {
+			team = "team-b"
+		}
+		nsList.Items[i].Labels = map[string]string{"team": team}
+	}
+
+	teamIndexFunc := func(obj interface{}) ([]string, error) {
+		return []string{obj.(*corev1.Namespace).Labels["team"]}, nil
+	}
+
+	tests := map[string]struct {
+		list     func(ctx context.Context) ([]runtime.Object, error)
+		expNames []string
+	}{
+		"Listing with a label selector should return the matching objects from the cache.": {
+			list: func(ctx context.Context) ([]runtime.Object, error) {
+				sel := labels.SelectorFromSet(labels.Set{"team": "team-a"})
+				return controller.ListerFromContext(ctx).List(sel)
+			},
+			expNames: []string{"testing-1", "testing-3", "testing-5"},
+		},
+
+		"Listing by a custom index should return the matching objects from the cache.": {
+			list: func(ctx context.Context) ([]runtime.Object, error) {
+				return controller.ListerFromContext(ctx).ByIndex("team", "team-b")
+			},
+			expNames: []string{"testing-0", "testing-2", "testing-4"},
+		},
+
+		"Getting an object should return the object from the cache.": {
+			list: func(ctx context.Context) ([]runtime.Object, error) {
+				obj, _, err := controller.ListerFromContext(ctx).Get("", "testing-3")
+				return []runtime.Object{obj}, err
+			},
+			expNames: []string{"testing-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			// Handle only once and get the objects from the lister.
+			var gotObjs []runtime.Object
+			var gotErr error
+			var once sync.Once
+			h := controller.HandlerFunc(func(ctx context.Context, _ runtime.Object) error {
+				once.Do(func() {
+					gotObjs, gotErr = test.list(ctx)
+					cancelCtx()
+				})
+				return nil
+			})
+
+			c, err := controller.New(&controller.Config{
+				Name:      "test",
+				Handler:   h,
+				Retriever: newNamespaceRetriever(mc),
+				Indexers:  cache.Indexers{"team": teamIndexFunc},
+				Logger:    log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			// Wait for different results. If no result means error failure.
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			require.NoError(gotErr)
+			gotNames := []string{}
+			for _, obj := range gotObjs {
+				gotNames = append(gotNames, obj.(*corev1.Namespace).Name)
+			}
+			assert.ElementsMatch(test.expNames, gotNames)
+		})
+	}
+}
+
+func TestTypedController(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		newController func(mc kubernetes.Interface, calls func(name string)) (controller.Controller, error)
+		expCalls      []string
+	}{
+		"A typed controller should handle the objects of its type.": {
+			newController: func(mc kubernetes.Interface, calls func(name string)) (controller.Controller, error) {
+				return controller.NewTyped(&controller.TypedConfig[*corev1.Namespace]{
+					Config: controller.Config{
+						Name:   "test",
+						Logger: log.Dummy,
+					},
+					Retriever: controller.NewTypedRetriever[*corev1.Namespace](newNamespaceRetriever(mc)),
+					Handler: controller.TypedHandlerFunc[*corev1.Namespace](func(_ context.Context, ns *corev1.Namespace) error {
+						calls(ns.Name)
+						return nil
+					}),
+				})
+			},
+			expCalls: []string{"testing-0", "testing-1", "testing-2", "testing-3", "testing-4"},
+		},
+
+		"A typed controller should not handle the objects of other types.": {
+			newController: func(mc kubernetes.Interface, calls func(name string)) (controller.Controller, error) {
+				return controller.NewTyped(&controller.TypedConfig[*corev1.Pod]{
+					Config: controller.Config{
+						Name:   "test",
+						Logger: log.Dummy,
+					},
+					Retriever: controller.NewTypedRetriever[*corev1.Pod](newNamespaceRetriever(mc)),
+					Handler: controller.TypedHandlerFunc[*corev1.Pod](func(_ context.Context, pod *corev1.Pod) error {
+						calls(pod.Name)
+						return nil
+					}),
+				})
+			},
+			expCalls: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			var mu sync.Mutex
+			gotCalls := []string{}
+			c, err := test.newController(mc, func(name string) {
+				mu.Lock()
+				defer mu.Unlock()
+				gotCalls = append(gotCalls, name)
+				if len(gotCalls) == len(test.expCalls) {
+					cancelCtx()
+				}
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			// If we don't expect calls, wait until the controller is watching the resources and give
+			// some time to the controller to process the objects.
+			if len(test.expCalls) == 0 {
+				require.Eventually(func() bool {
+					for _, a := range mc.Actions() {
+						if a.GetVerb() == "watch" {
+							return true
+						}
+					}
+					return false
+				}, time.Second, 10*time.Millisecond)
+				time.Sleep(100 * time.Millisecond)
+				cancelCtx()
+			}
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			mu.Lock()
+			assert.ElementsMatch(test.expCalls, gotCalls)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestGenericControllerWatches(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 5)
+
+	tests := map[string]struct {
+		mapper  controller.EnqueueMapper
+		pod     *corev1.Pod
+		expKeys []string
+	}{
+		"A secondary resource event mapped by a custom function should handle the primary resource object.": {
+			mapper: controller.EnqueueMapperFunc(func(_ context.Context, obj runtime.Object) ([]string, error) {
+				return []string{obj.(*corev1.Pod).Namespace}, nil
+			}),
+			pod:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "testing-2"}},
+			expKeys: []string{"testing-2"},
+		},
+
+		"A secondary resource event mapped to multiple keys should handle all the primary resource objects.": {
+			mapper: controller.EnqueueMapperFunc(func(_ context.Context, obj runtime.Object) ([]string, error) {
+				return []string{"testing-1", "testing-3"}, nil
+			}),
+			pod:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			expKeys: []string{"testing-1", "testing-3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+			podRet := controller.MustRetrieverFromListerWatcher(&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return mc.CoreV1().Pods("").List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return mc.CoreV1().Pods("").Watch(context.TODO(), options)
+				},
+			})
+
+			// Once all the primary objects have been handled, create the secondary object.
+			var mu sync.Mutex
+			handled := 0
+			gotKeys := []string{}
+			h := controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
+				mu.Lock()
+				defer mu.Unlock()
+				handled++
+				switch {
+				case handled == len(nsList.Items):
+					go func() {
+						// Give time to the secondary resource watch to be ready.
+						time.Sleep(50 * time.Millisecond)
+						_, err := mc.CoreV1().Pods(test.pod.Namespace).Create(context.TODO(), test.pod, metav1.CreateOptions{})
+						assert.NoError(err)
+					}()
+				case handled > len(nsList.Items):
+					gotKeys = append(gotKeys, obj.(*corev1.Namespace).Name)
+					if len(gotKeys) == len(test.expKeys) {
+						cancelCtx()
+					}
+				}
+				return nil
+			})
+
+			c, err := controller.New(&controller.Config{
+				Name:      "test",
+				Handler:   h,
+				Retriever: newNamespaceRetriever(mc),
+				Watches: []controller.Watch{
+					{Retriever: podRet, Mapper: test.mapper},
+				},
+				Logger: log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			mu.Lock()
+			assert.ElementsMatch(test.expKeys, gotKeys)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestGenericControllerGracefulShutdown(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 1)
+
+	tests := map[string]struct {
+		shutdownTimeout time.Duration
+		handleDuration  time.Duration
+		ignoreCancel    bool
+		expFinished     bool
+		expCancelled    bool
+	}{
+		"Without shutdown timeout, the in-flight handlers context should be cancelled when stopping.": {
+			shutdownTimeout: 0,
+			handleDuration:  time.Second,
+			expFinished:     false,
+			expCancelled:    true,
+		},
+
+		"With shutdown timeout, the in-flight handlers should finish before stopping.": {
+			shutdownTimeout: time.Second,
+			handleDuration:  100 * time.Millisecond,
+			expFinished:     true,
+			expCancelled:    false,
+		},
+
+		"With shutdown timeout, the in-flight handlers context should be cancelled after the timeout.": {
+			shutdownTimeout: 50 * time.Millisecond,
+			handleDuration:  time.Second,
+			expFinished:     false,
+			expCancelled:    true,
+		},
+
+		"With shutdown timeout, the in-flight handlers ignoring the cancellation should not block the stop.": {
+			shutdownTimeout: 50 * time.Millisecond,
+			handleDuration:  5 * time.Second,
+			ignoreCancel:    true,
+			expFinished:     false,
+			expCancelled:    false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			// Stop the controller while handling.
+			var mu sync.Mutex
+			finished, cancelled := false, false
+			h := controller.HandlerFunc(func(ctx context.Context, _ runtime.Object) error {
+				cancelCtx()
+				if test.ignoreCancel {
+					ctx = context.WithoutCancel(ctx)
+				}
+				select {
+				case <-time.After(test.handleDuration):
+					mu.Lock()
+					finished = true
+					mu.Unlock()
+				case <-ctx.Done():
+					mu.Lock()
+					cancelled = true
+					mu.Unlock()
+				}
+				return nil
+			})
+
+			c, err := controller.New(&controller.Config{
+				Name:            "test",
+				Handler:         h,
+				Retriever:       newNamespaceRetriever(mc),
+				ShutdownTimeout: test.shutdownTimeout,
+				Logger:          log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			// The controller should wait for the handlers that finish before the shutdown timeout, the
+			// others are cancelled and abandoned, so they could end after the controller stops.
+			mu.Lock()
+			assert.Equal(test.expFinished, finished)
+			mu.Unlock()
+			assert.Eventually(func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return cancelled == test.expCancelled
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
+
+type timeoutsMetricsRecorder struct {
+	controller.MetricsRecorder
+	mu       sync.Mutex
+	timeouts int
+}
+
+func (t *timeoutsMetricsRecorder) IncResourceProcessingTimeout(context.Context, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeouts++
+}
+
+func TestGenericControllerHandlerTimeout(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 1)
+
+	tests := map[string]struct {
+		handlerTimeout  time.Duration
+		shutdownTimeout time.Duration
+		hangDuration    time.Duration
+		retries         int
+		expCalls        int
+		expTimeouts     int
+		expHandling     int
+	}{
+		"A hung handler should timeout and be retried.": {
+			handlerTimeout:  50 * time.Millisecond,
+			shutdownTimeout: time.Second,
+			hangDuration:    100 * time.Millisecond,
+			retries:         2,
+			expCalls:        3,
+			expTimeouts:     3,
+			expHandling:     0,
+		},
+
+		"A hung handler ignoring the context should not be handled concurrently while it's running.": {
+			handlerTimeout:  10 * time.Millisecond,
+			shutdownTimeout: time.Second,
+			hangDuration:    100 * time.Millisecond,
+			retries:         5,
+			expCalls:        4,
+			expTimeouts:     4,
+			expHandling:     0,
+		},
+
+		"A hung handler that doesn't return should not block the stop after the shutdown timeout.": {
+			handlerTimeout:  10 * time.Millisecond,
+			shutdownTimeout: 50 * time.Millisecond,
+			hangDuration:    5 * time.Second,
+			retries:         0,
+			expCalls:        1,
+			expTimeouts:     1,
+			expHandling:     1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			// Hang the handler ignoring the context, stop the controller on the last call.
+			var mu sync.Mutex
+			calls, handling, maxHandling := 0, 0, 0
+			h := controller.HandlerFunc(func(ctx context.Context, _ runtime.Object) error {
+				mu.Lock()
+				calls++
+				handling++
+				maxHandling = max(maxHandling, handling)
+				c := calls
+				mu.Unlock()
+				defer func() {
+					mu.Lock()
+					handling--
+					mu.Unlock()
+				}()
+
+				if c == test.expCalls {
+					cancelCtx()
+				}
+				time.Sleep(test.hangDuration)
+				return nil
+			})
+
+			mrec := &timeoutsMetricsRecorder{MetricsRecorder: controller.DummyMetricsRecorder}
+			c, err := controller.New(&controller.Config{
+				Name:                 "test",
+				Handler:              h,
+				Retriever:            newNamespaceRetriever(mc),
+				HandlerTimeout:       test.handlerTimeout,
+				ShutdownTimeout:      test.shutdownTimeout,
+				ProcessingJobRetries: test.retries,
+				MetricsRecorder:      mrec,
+				Logger:               log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			// The same object should not be handled concurrently, and the controller should wait for
+			// the hung handlers when stopping up to the shutdown timeout.
+			mu.Lock()
+			assert.Equal(test.expCalls, calls)
+			assert.Equal(1, maxHandling)
+			assert.Equal(test.expHandling, handling)
+			mu.Unlock()
+			mrec.mu.Lock()
+			assert.Equal(test.expTimeouts, mrec.timeouts)
+			mrec.mu.Unlock()
+		})
+	}
+}
+
+type panicsMetricsRecorder struct {
+	controller.MetricsRecorder
+	mu     sync.Mutex
+	panics int
+}
+
+func (p *panicsMetricsRecorder) IncResourceProcessingPanic(context.Context, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.panics++
+}
+
+func TestGenericControllerHandlerPanic(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 1)
+
+	tests := map[string]struct {
+		handlerTimeout time.Duration
+		retries        int
+		expCalls       int
+		expPanics      int
+	}{
+		"A panicking handler should be recovered and retried.": {
+			retries:   2,
+			expCalls:  3,
+			expPanics: 2,
+		},
+
+		"A panicking handler with handler timeout should be recovered and retried.": {
+			handlerTimeout: time.Second,
+			retries:        2,
+			expCalls:       3,
+			expPanics:      2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			// Panic on the handler until the last call.
+			var mu sync.Mutex
+			calls := 0
+			h := controller.HandlerFunc(func(ctx context.Context, _ runtime.Object) error {
+				mu.Lock()
+				calls++
+				c := calls
+				mu.Unlock()
+
+				if c == test.expCalls {
+					cancelCtx()
+					return nil
+				}
+				panic("wanted panic")
+			})
+
+			mrec := &panicsMetricsRecorder{MetricsRecorder: controller.DummyMetricsRecorder}
+			c, err := controller.New(&controller.Config{
+				Name:                 "test",
+				Handler:              h,
+				Retriever:            newNamespaceRetriever(mc),
+				HandlerTimeout:       test.handlerTimeout,
+				ProcessingJobRetries: test.retries,
+				MetricsRecorder:      mrec,
+				Logger:               log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			mu.Lock()
+			assert.Equal(test.expCalls, calls)
+			mu.Unlock()
+			mrec.mu.Lock()
+			assert.Equal(test.expPanics, mrec.panics)
+			mrec.mu.Unlock()
+		})
+	}
+}
+
+type filteredMetricsRecorder struct {
+	controller.MetricsRecorder
+	mu       sync.Mutex
+	filtered int
+}
+
+func (f *filteredMetricsRecorder) IncResourceEventFiltered(context.Context, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.filtered++
+}
+
+func TestGenericControllerPredicates(t *testing.T) {
+	nsList, _ := createNamespaceList("testing", 4)
+
+	tests := map[string]struct {
+		predicates  []controller.Predicate
+		expHandled  []string
+		expFiltered int
+	}{
+		"Without predicates all the objects should be handled.": {
+			expHandled: []string{"testing-0", "testing-1", "testing-2", "testing-3"},
+		},
+
+		"With predicates only the allowed objects should be handled.": {
+			predicates: []controller.Predicate{
+				controller.PredicateFunc(func(ev controller.Event) bool {
+					ns := ev.Object.(*corev1.Namespace)
+					return ns.Name != "testing-1"
+				}),
+				controller.PredicateFunc(func(ev controller.Event) bool {
+					ns := ev.Object.(*corev1.Namespace)
+					return ns.Name != "testing-2"
+				}),
+			},
+			expHandled:  []string{"testing-0", "testing-3"},
+			expFiltered: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx, cancelCtx := context.WithCancel(context.Background())
+			defer cancelCtx()
+			resultC := make(chan error)
+
+			// Mocks kubernetes  client.
+			mc := fake.NewSimpleClientset(nsList)
+
+			var mu sync.Mutex
+			handled := []string{}
+			h := controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
+				mu.Lock()
+				defer mu.Unlock()
+				handled = append(handled, obj.(*corev1.Namespace).Name)
+				if len(handled) == len(test.expHandled) {
+					cancelCtx()
+				}
+				return nil
+			})
+
+			mrec := &filteredMetricsRecorder{MetricsRecorder: controller.DummyMetricsRecorder}
+			c, err := controller.New(&controller.Config{
+				Name:            "test",
+				Handler:         h,
+				Retriever:       newNamespaceRetriever(mc),
+				Predicates:      test.predicates,
+				MetricsRecorder: mrec,
+				Logger:          log.Dummy,
+			})
+			require.NoError(err)
+
+			// Run Controller in background.
+			go func() {
+				resultC <- c.Run(ctx)
+			}()
+
+			select {
+			case err := <-resultC:
+				require.NoError(err)
+			case <-time.After(1 * time.Second):
+				require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+			}
+
+			mu.Lock()
+			assert.ElementsMatch(test.expHandled, handled)
+			mu.Unlock()
+			mrec.mu.Lock()
+			assert.Equal(test.expFiltered, mrec.filtered)
+			mrec.mu.Unlock()
+		})
+	}
+}
+
+func TestGenericControllerObjectTransform(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	resultC := make(chan error)
+
+	nsList, _ := createNamespaceList("testing", 1)
+	nsList.Items[0].ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	nsList.Items[0].Annotations = map[string]string{controller.LastAppliedConfigAnnotation: "{}"}
+	mc := fake.NewSimpleClientset(nsList)
+
+	var gotNS *corev1.Namespace
+	h := controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
+		gotNS = obj.(*corev1.Namespace)
+		cancelCtx()
+		return nil
+	})
+
+	c, err := controller.New(&controller.Config{
+		Name:            "test",
+		Handler:         h,
+		Retriever:       newNamespaceRetriever(mc),
+		ObjectTransform: controller.TransformStripManagedFields,
+		Logger:          log.Dummy,
+	})
+	require.NoError(err)
+
+	// Run Controller in background.
+	go func() {
+		resultC <- c.Run(ctx)
+	}()
+
+	select {
+	case err := <-resultC:
+		require.NoError(err)
+	case <-time.After(1 * time.Second):
+		require.Fail("timeout waiting for controller handling, this could mean the controller is not receiving resources")
+	}
+
+	require.NotNil(gotNS)
+	assert.Equal("testing-0", gotNS.Name)
+	assert.Empty(gotNS.ManagedFields)
+	assert.Empty(gotNS.Annotations)
+}
+
+// testSharder is a sharder that owns the configured keys.
+type testSharder struct {
+	mu       sync.Mutex
+	owned    map[string]bool
+	onChange func(gained func(key string) bool)
+	runningC chan struct{}
+}
+
+func (t *testSharder) Owns(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.owned[key]
+}
+
+func (t *testSharder) Run(ctx context.Context, onChange func(gained func(key string) bool)) error {
+	t.mu.Lock()
+	t.onChange = onChange
+	t.mu.Unlock()
+	close(t.runningC)
+	<-ctx.Done()
+	return nil
+}
+
+// rebalance sets the new owned keys and notifies the change.
+func (t *testSharder) rebalance(owned map[string]bool) {
+	t.mu.Lock()
+	prevOwned := t.owned
+	t.owned = owned
+	onChange := t.onChange
+	t.mu.Unlock()
+	onChange(func(key string) bool { return owned[key] && !prevOwned[key] })
+}
+
+func TestGenericControllerSharding(t *testing.T) {
+	t.Run("Using sharding with leader election should fail.", func(t *testing.T) {
+		_, err := controller.New(&controller.Config{
+			Name:          "test",
+			Handler:       controller.HandlerFunc(func(context.Context, runtime.Object) error { return nil }),
+			Retriever:     newNamespaceRetriever(fake.NewSimpleClientset()),
+			Sharder:       &testSharder{},
+			LeaderElector: blockingLeaderElector{},
+			Logger:        log.Dummy,
+		})
+		assert.ErrorIs(t, err, controller.ErrControllerNotValid)
+	})
+
+	t.Run("The controller should only handle the owned keys and the new owned keys after a rebalance.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx, cancelCtx := context.WithCancel(context.Background())
+		defer cancelCtx()
+
+		nsList, _ := createNamespaceList("testing", 4)
+		mc := fake.NewSimpleClientset(nsList)
+
+		var mu sync.Mutex
+		handled := map[string]int{}
+		h := controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[obj.(*corev1.Namespace).Name]++
+			return nil
+		})
+		getHandled := func() map[string]int {
+			mu.Lock()
+			defer mu.Unlock()
+			return maps.Clone(handled)
+		}
+
+		sharder := &testSharder{
+			owned:    map[string]bool{"testing-0": true, "testing-2": true},
+			runningC: make(chan struct{}),
+		}
+		c, err := controller.New(&controller.Config{
+			Name:      "test",
+			Handler:   h,
+			Retriever: newNamespaceRetriever(mc),
+			Sharder:   sharder,
+			Logger:    log.Dummy,
+		})
+		require.NoError(err)
+
+		resultC := make(chan error)
+		go func() { resultC <- c.Run(ctx) }()
+
+		// Only the owned keys should be handled.
+		<-sharder.runningC
+		waitCondition(t, func() bool { return len(getHandled()) == 2 })
+		assert.Equal(map[string]int{"testing-0": 1, "testing-2": 1}, getHandled())
+
+		// Rebalance and check only the new owned keys are handled.
+		sharder.rebalance(map[string]bool{"testing-2": true, "testing-3": true})
+		waitCondition(t, func() bool { return getHandled()["testing-3"] == 1 })
+		assert.Equal(map[string]int{"testing-0": 1, "testing-2": 1, "testing-3": 1}, getHandled())
+
+		cancelCtx()
+		select {
+		case err := <-resultC:
+			require.NoError(err)
+		case <-time.After(1 * time.Second):
+			require.Fail("timeout waiting for controller to stop")
+		}
+	})
+}
+
+func TestGenericControllerLeaderElectionFailover(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	nsList, _ := createNamespaceList("testing", 3)
+	mc := fake.NewSimpleClientset(nsList)
+
+	// Leader election with an in-memory lock shared by the controllers.
+	store := leaderelection.NewMemoryLockStore("test")
+	newController := func(id string, handled *atomic.Int32) controller.Controller {
+		le, err := leaderelection.NewWithConfig(leaderelection.Config{
+			Key:  "test",
+			Lock: leaderelection.NewMemoryLock(store, id),
+			LockConfig: &leaderelection.LockConfig{
+				LeaseDuration: 1 * time.Second,
+				RenewDeadline: 500 * time.Millisecond,
+				RetryPeriod:   20 * time.Millisecond,
+			},
+			Logger: log.Dummy,
+		})
+		require.NoError(err)
+
+		c, err := controller.New(&controller.Config{
+			Name: id,
+			Handler: controller.HandlerFunc(func(context.Context, runtime.Object) error {
+				handled.Add(1)
+				return nil
+			}),
+			Retriever:     newNamespaceRetriever(mc),
+			LeaderElector: le,
+			Logger:        log.Dummy,
+		})
+		require.NoError(err)
+		return c
+	}
+
+	var handled1, handled2 atomic.Int32
+	c1 := newController("c1", &handled1)
+	c2 := newController("c2", &handled2)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	resultC1 := make(chan error, 1)
+	resultC2 := make(chan error, 1)
+
+	// The first controller should be the leader and handle the objects.
+	go func() { resultC1 <- c1.Run(ctx1) }()
+	waitCondition(t, func() bool { return handled1.Load() == 3 })
+	go func() { resultC2 <- c2.Run(ctx2) }()
+	assert.Never(func() bool { return handled2.Load() > 0 }, 200*time.Millisecond, 10*time.Millisecond)
+
+	// Stopping the leader should release the leadership and the second one should handle the objects.
+	cancel1()
+	require.NoError(<-resultC1)
+	require.Eventually(func() bool { return handled2.Load() == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	cancel2()
+	require.NoError(<-resultC2)
+}
+
+func TestGenericControllerLeaderElectionReenter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nsList, _ := createNamespaceList("testing", 2)
+	mc := fake.NewSimpleClientset(nsList)
+
+	le, err := leaderelection.NewWithConfig(leaderelection.Config{
+		Key:              "test",
+		Namespace:        "test-ns",
+		KubernetesClient: mc,
+		Identity:         "c1",
+		LockConfig: &leaderelection.LockConfig{
+			LeaseDuration: 150 * time.Millisecond,
+			RenewDeadline: 100 * time.Millisecond,
+			RetryPeriod:   10 * time.Millisecond,
+		},
+		ReenterElection: true,
+		Logger:          log.Dummy,
+	})
+	require.NoError(err)
+
+	var mu sync.Mutex
+	handled := map[string]bool{}
+	isHandled := func(names ...string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, name := range names {
+			if !handled[name] {
+				return false
+			}
+		}
+		return true
+	}
+	c, err := controller.New(&controller.Config{
+		Name: "test",
+		Handler: controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[obj.(*corev1.Namespace).Name] = true
+			return nil
+		}),
+		Retriever:     newNamespaceRetriever(mc),
+		LeaderElector: le,
+		Logger:        log.Dummy,
+	})
+	require.NoError(err)
+
+	resultC := make(chan error, 1)
+	go func() { resultC <- c.Run(ctx) }()
+	waitCondition(t, func() bool { return isHandled("testing-0", "testing-1") })
+
+	// Steal the lease, the controller should stop and run again once it acquires the leadership back.
+	lease, err := mc.CoordinationV1().Leases("test-ns").Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(err)
+	now := metav1.NewMicroTime(time.Now())
+	holder, leaseDuration := "other", int32(1)
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &leaseDuration
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	_, err = mc.CoordinationV1().Leases("test-ns").Update(ctx, lease, metav1.UpdateOptions{})
+	require.NoError(err)
+	require.Eventually(func() bool { return !le.IsLeader() }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(func() bool { return le.IsLeader() && c.Ready(ctx) == nil }, 3*time.Second, 10*time.Millisecond)
+
+	// The new events should be handled after re-entering.
+	_, err = mc.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "testing-new"}}, metav1.CreateOptions{})
+	require.NoError(err)
+	waitCondition(t, func() bool { return isHandled("testing-new") })
+	assert.NoError(c.Ready(ctx))
+	assert.NoError(c.Health(ctx))
+
+	cancel()
+	require.NoError(<-resultC)
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// deletedObjectStore stores the last known state of the deleted objects until
+// these are handled by the delete handler.
+type deletedObjectStore struct {
+	mu   sync.Mutex
+	objs map[string]runtime.Object
+}
+
+func newDeletedObjectStore() *deletedObjectStore {
+	return &deletedObjectStore{
+		objs: map[string]runtime.Object{},
+	}
+}
+
+func (d *deletedObjectStore) Set(key string, obj runtime.Object) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.objs[key] = obj
+}
+
+func (d *deletedObjectStore) Get(key string) (runtime.Object, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	obj, ok := d.objs[key]
+	return obj, ok
+}
+
+func (d *deletedObjectStore) Delete(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.objs, key)
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	// ErrNotHealthy will be used when the controller is not healthy.
+	ErrNotHealthy = errors.New("not healthy")
+	// ErrNotReady will be used when the controller is not ready.
+	ErrNotReady = errors.New("not ready")
+)
+
+// HealthChecker knows how to check the health and readiness of a component (e.g a controller).
+type HealthChecker interface {
+	// Health returns an error if the component is not healthy and needs to be restarted (liveness).
+	Health(ctx context.Context) error
+	// Ready returns an error if the component is not ready to do its job (readiness).
+	Ready(ctx context.Context) error
+}
+
+// NewHealthHandler returns an HTTP handler that exposes the health (`/healthz`) and the readiness (`/readyz`)
+// of the checkers (e.g controllers), so they can be used by Kubernetes probes. When all the checkers
+// are healthy/ready it will respond with a 200, otherwise with a 503 and the errors.
+func NewHealthHandler(checkers ...HealthChecker) http.Handler {
+	check := func(f func(HealthChecker, context.Context) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			errs := []error{}
+			for _, c := range checkers {
+				if err := f(c, r.Context()); err != nil {
+					errs = append(errs, err)
+				}
+			}
+
+			if len(errs) > 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = fmt.Fprintln(w, errors.Join(errs...))
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = fmt.Fprintln(w, "ok")
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", check(HealthChecker.Health))
+	mux.Handle("/readyz", check(HealthChecker.Ready))
+
+	return mux
+}
+
+// watchHealth tracks the errors of the informers list and watch.
+type watchHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (w *watchHealth) setError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
+
+func (w *watchHealth) error() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// healthTrackedRetriever clears the tracked errors once the watch succeeds again (a successful list is
+// always followed by a watch).
+type healthTrackedRetriever struct {
+	next   Retriever
+	health *watchHealth
+}
+
+func newHealthTrackedRetriever(next Retriever, health *watchHealth) Retriever {
+	return healthTrackedRetriever{next: next, health: health}
+}
+
+func (h healthTrackedRetriever) List(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+	return h.next.List(ctx, options)
+}
+
+func (h healthTrackedRetriever) Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	w, err := h.next.Watch(ctx, options)
+	if err == nil {
+		h.health.setError(nil)
+	}
+	return w, err
+}
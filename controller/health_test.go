@@ -0,0 +1,204 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+type testHealthChecker struct {
+	healthErr error
+	readyErr  error
+}
+
+func (t testHealthChecker) Health(context.Context) error { return t.healthErr }
+func (t testHealthChecker) Ready(context.Context) error  { return t.readyErr }
+
+func TestHealthHandler(t *testing.T) {
+	tests := map[string]struct {
+		checkers  []controller.HealthChecker
+		path      string
+		expStatus int
+	}{
+		"Healthy checkers should respond with OK on health.": {
+			checkers:  []controller.HealthChecker{testHealthChecker{}, testHealthChecker{readyErr: errors.New("wanted error")}},
+			path:      "/healthz",
+			expStatus: http.StatusOK,
+		},
+
+		"Not healthy checkers should respond with unavailable on health.": {
+			checkers:  []controller.HealthChecker{testHealthChecker{}, testHealthChecker{healthErr: errors.New("wanted error")}},
+			path:      "/healthz",
+			expStatus: http.StatusServiceUnavailable,
+		},
+
+		"Ready checkers should respond with OK on readiness.": {
+			checkers:  []controller.HealthChecker{testHealthChecker{}, testHealthChecker{healthErr: errors.New("wanted error")}},
+			path:      "/readyz",
+			expStatus: http.StatusOK,
+		},
+
+		"Not ready checkers should respond with unavailable on readiness.": {
+			checkers:  []controller.HealthChecker{testHealthChecker{readyErr: errors.New("wanted error")}, testHealthChecker{}},
+			path:      "/readyz",
+			expStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := controller.NewHealthHandler(test.checkers...)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.expStatus, w.Code)
+		})
+	}
+}
+
+// blockingLeaderElector is a leader election runner that never gets the leadership.
+type blockingLeaderElector struct{}
+
+func (blockingLeaderElector) Run(ctx context.Context, _ func(context.Context) error) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (blockingLeaderElector) Leader() string { return "other" }
+func (blockingLeaderElector) IsLeader() bool { return false }
+
+func waitCondition(t *testing.T, cond func() bool) {
+	t.Helper()
+	require.Eventually(t, cond, time.Second, 10*time.Millisecond)
+}
+
+func TestGenericControllerHealth(t *testing.T) {
+	t.Run("A running controller should be healthy and ready.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		nsList, _ := createNamespaceList("testing", 1)
+		c, err := controller.New(&controller.Config{
+			Name:      "test",
+			Handler:   controller.HandlerFunc(func(context.Context, runtime.Object) error { return nil }),
+			Retriever: newNamespaceRetriever(fake.NewSimpleClientset(nsList)),
+			Logger:    log.Dummy,
+		})
+		require.NoError(err)
+
+		// Not running.
+		assert.ErrorIs(c.Ready(ctx), controller.ErrNotReady)
+		assert.NoError(c.Health(ctx))
+
+		resultC := make(chan error)
+		go func() { resultC <- c.Run(ctx) }()
+		waitCondition(t, func() bool { return c.Ready(ctx) == nil })
+		assert.NoError(c.Health(ctx))
+
+		// Stopped.
+		cancel()
+		require.NoError(<-resultC)
+		assert.ErrorIs(c.Ready(context.Background()), controller.ErrNotReady)
+	})
+
+	t.Run("A controller with a failing watch should not be ready.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		nsList, _ := createNamespaceList("testing", 1)
+		ret := controller.MustRetrieverFromListerWatcher(&cache.ListWatch{
+			ListFunc: func(metav1.ListOptions) (runtime.Object, error) { return nsList, nil },
+			WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+				return nil, errors.New("wanted error")
+			},
+		})
+		c, err := controller.New(&controller.Config{
+			Name:      "test",
+			Handler:   controller.HandlerFunc(func(context.Context, runtime.Object) error { return nil }),
+			Retriever: ret,
+			Logger:    log.Dummy,
+		})
+		require.NoError(err)
+
+		go func() { _ = c.Run(ctx) }()
+		waitCondition(t, func() bool {
+			err := c.Ready(ctx)
+			return err != nil && strings.Contains(err.Error(), "watch is failing")
+		})
+		assert.ErrorIs(c.Ready(ctx), controller.ErrNotReady)
+		assert.NoError(c.Health(ctx))
+	})
+
+	t.Run("A controller with a worker stuck handling an object should not be healthy.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		nsList, _ := createNamespaceList("testing", 1)
+		c, err := controller.New(&controller.Config{
+			Name: "test",
+			Handler: controller.HandlerFunc(func(ctx context.Context, _ runtime.Object) error {
+				<-ctx.Done()
+				return nil
+			}),
+			Retriever:              newNamespaceRetriever(fake.NewSimpleClientset(nsList)),
+			StuckHandlingThreshold: 50 * time.Millisecond,
+			Logger:                 log.Dummy,
+		})
+		require.NoError(err)
+
+		resultC := make(chan error)
+		go func() { resultC <- c.Run(ctx) }()
+		waitCondition(t, func() bool { return c.Health(ctx) != nil })
+		assert.ErrorIs(c.Health(ctx), controller.ErrNotHealthy)
+		assert.NoError(c.Ready(ctx))
+
+		// Stopped.
+		cancel()
+		require.NoError(<-resultC)
+		assert.NoError(c.Health(context.Background()))
+	})
+
+	t.Run("A controller waiting for the leadership should be ready.", func(t *testing.T) {
+		assert := assert.New(t)
+		require := require.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		nsList, _ := createNamespaceList("testing", 1)
+		le := blockingLeaderElector{}
+		c, err := controller.New(&controller.Config{
+			Name:          "test",
+			Handler:       controller.HandlerFunc(func(context.Context, runtime.Object) error { return nil }),
+			Retriever:     newNamespaceRetriever(fake.NewSimpleClientset(nsList)),
+			LeaderElector: le,
+			Logger:        log.Dummy,
+		})
+		require.NoError(err)
+
+		go func() { _ = c.Run(ctx) }()
+		waitCondition(t, func() bool { return c.Ready(ctx) == nil })
+		assert.NoError(c.Health(ctx))
+	})
+}
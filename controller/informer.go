@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// SharedInformers shares the informers (list, watch and local cache) between multiple controllers
+// of the same resource type, instead of each controller having its own informer.
+//
+// The shared informers are reference counted, the informer will start running with the first
+// controller that runs and stop when the last controller using it stops.
+type SharedInformers struct {
+	mu        sync.Mutex
+	informers map[string]*refCountedInformer
+}
+
+// NewSharedInformers returns a new SharedInformers that can be used in multiple controller configurations.
+func NewSharedInformers() *SharedInformers {
+	return &SharedInformers{
+		informers: map[string]*refCountedInformer{},
+	}
+}
+
+// informer returns the informer for the key, if it doesn't exist it will create a new one using the
+// received retriever and transform.
+func (s *SharedInformers) informer(key string, ret Retriever, resyncInterval time.Duration, transform cache.TransformFunc) (*refCountedInformer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inf, ok := s.informers[key]
+	if ok {
+		return inf, nil
+	}
+
+	inf, err := newInformer(ret, resyncInterval, transform)
+	if err != nil {
+		return nil, err
+	}
+	inf.onStop = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// Only remove if it's still ours, once stopped, an informer can't be run again.
+		if s.informers[key] == inf {
+			delete(s.informers, key)
+		}
+	}
+	s.informers[key] = inf
+
+	return inf, nil
+}
+
+// newInformer returns a new reference counted informer, the transform is optional and it's set at creation
+// because it can't be set once the informer has started. The list and watch errors of the informer are
+// tracked for the controller health checks.
+func newInformer(ret Retriever, resyncInterval time.Duration, transform cache.TransformFunc) (*refCountedInformer, error) {
+	health := &watchHealth{}
+	lw := listerWatcherFromRetriever(newHealthTrackedRetriever(ret, health))
+	indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+	informer := cache.NewSharedIndexInformer(lw, nil, resyncInterval, indexers)
+
+	if transform != nil {
+		err := informer.SetTransform(transform)
+		if err != nil {
+			return nil, fmt.Errorf("could not set informer transform: %w", err)
+		}
+	}
+
+	err := informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *cache.Reflector, err error) {
+		health.setError(err)
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not set informer watch error handler: %w", err)
+	}
+
+	return &refCountedInformer{informer: informer, health: health}, nil
+}
+
+// refCountedInformer will run the informer when the first user acquires it and stop it
+// when the last user releases it.
+type refCountedInformer struct {
+	informer cache.SharedIndexInformer
+	health   *watchHealth
+	onStop   func()
+
+	mu      sync.Mutex
+	refs    int
+	stopC   chan struct{}
+	stopped bool
+}
+
+// addIndexers adds the indexers to the informer, the ones that already exist (e.g
+// already added by other controller) will be ignored.
+func (r *refCountedInformer) addIndexers(indexers cache.Indexers) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.informer.GetIndexer().GetIndexers()
+	newIndexers := cache.Indexers{}
+	for name, f := range indexers {
+		if _, ok := current[name]; !ok {
+			newIndexers[name] = f
+		}
+	}
+
+	if len(newIndexers) == 0 {
+		return nil
+	}
+
+	return r.informer.AddIndexers(newIndexers)
+}
+
+// acquire runs the informer if it's the first user, it returns false if the informer has been
+// stopped, once stopped, an informer can't be run again.
+func (r *refCountedInformer) acquire() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopped {
+		return false
+	}
+
+	r.refs++
+	if r.refs == 1 {
+		r.stopC = make(chan struct{})
+		go r.informer.Run(r.stopC)
+	}
+
+	return true
+}
+
+func (r *refCountedInformer) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.refs == 0 {
+		return
+	}
+
+	r.refs--
+	if r.refs == 0 {
+		close(r.stopC)
+		r.stopped = true
+		if r.onStop != nil {
+			r.onStop()
+		}
+	}
+}
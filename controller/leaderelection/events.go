@@ -0,0 +1,56 @@
+package leaderelection
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// kubernetesEventRecorder sends the leader election events to Kubernetes while it's started.
+type kubernetesEventRecorder struct {
+	k8scli    kubernetes.Interface
+	namespace string
+	source    v1.EventSource
+
+	mu       sync.Mutex
+	recorder record.EventRecorder
+}
+
+func newKubernetesEventRecorder(k8scli kubernetes.Interface, namespace string, source v1.EventSource) *kubernetesEventRecorder {
+	return &kubernetesEventRecorder{
+		k8scli:    k8scli,
+		namespace: namespace,
+		source:    source,
+	}
+}
+
+func (k *kubernetesEventRecorder) Eventf(obj runtime.Object, eventType, reason, message string, args ...interface{}) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.recorder != nil {
+		k.recorder.Eventf(obj, eventType, reason, message, args...)
+	}
+}
+
+// start starts sending the events to Kubernetes, returns a function to stop it. The broadcaster
+// is shut down on stop (a stopped broadcaster can't be reused), so it's created on every start.
+func (k *kubernetesEventRecorder) start() (stop func()) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k.k8scli.CoreV1().Events(k.namespace)})
+
+	k.mu.Lock()
+	k.recorder = broadcaster.NewRecorder(scheme.Scheme, k.source)
+	k.mu.Unlock()
+
+	return func() {
+		k.mu.Lock()
+		k.recorder = nil
+		k.mu.Unlock()
+		broadcaster.Shutdown()
+	}
+}
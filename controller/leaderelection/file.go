@@ -0,0 +1,178 @@
+package leaderelection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// fileLockStaleTimeout is the time after a lock sidecar file is considered abandoned (e.g a process crashed).
+	fileLockStaleTimeout = 10 * time.Second
+	fileLockRetryPeriod  = 10 * time.Millisecond
+)
+
+var fileLockResource = schema.GroupResource{Group: "kooper.spotahome.com", Resource: "filelocks"}
+
+// fileLock is a lock that stores the record on a file.
+type fileLock struct {
+	path     string
+	identity string
+
+	mu       sync.Mutex
+	observed []byte
+}
+
+// NewFileLock returns a lock for the identity candidate that stores the record on a file, the writes
+// are protected by a sidecar file (`{path}.lock`) created exclusively. The lock works between processes
+// of the same machine, it's useful for local development without a Kubernetes cluster.
+func NewFileLock(path, identity string) (Lock, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a path is required")
+	}
+
+	if identity == "" {
+		return nil, fmt.Errorf("an identity is required")
+	}
+
+	return &fileLock{
+		path:     path,
+		identity: identity,
+	}, nil
+}
+
+func (f *fileLock) Get(_ context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	raw, err := f.read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := decodeLockData(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	f.setObserved(raw)
+
+	return &data.Record, raw, nil
+}
+
+func (f *fileLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	raw, err := encodeLockData(1, ler)
+	if err != nil {
+		return err
+	}
+
+	return f.withLock(ctx, func() error {
+		_, err := f.read()
+		if err == nil {
+			return kubeerrors.NewAlreadyExists(fileLockResource, f.path)
+		}
+		if !kubeerrors.IsNotFound(err) {
+			return err
+		}
+
+		return f.write(raw)
+	})
+}
+
+func (f *fileLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return f.withLock(ctx, func() error {
+		currentRaw, err := f.read()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(currentRaw, f.getObserved()) {
+			return kubeerrors.NewConflict(fileLockResource, f.path, fmt.Errorf("the lock has been modified"))
+		}
+
+		current, err := decodeLockData(currentRaw)
+		if err != nil {
+			return err
+		}
+		raw, err := encodeLockData(current.Version+1, ler)
+		if err != nil {
+			return err
+		}
+
+		return f.write(raw)
+	})
+}
+
+func (f *fileLock) RecordEvent(string) {}
+func (f *fileLock) Identity() string   { return f.identity }
+func (f *fileLock) Describe() string   { return "file/" + f.path }
+
+func (f *fileLock) read() ([]byte, error) {
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, kubeerrors.NewNotFound(fileLockResource, f.path)
+		}
+		return nil, fmt.Errorf("could not read lock file: %w", err)
+	}
+
+	return raw, nil
+}
+
+// write writes the record atomically, it must be called holding the lock.
+func (f *fileLock) write(raw []byte) error {
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return fmt.Errorf("could not write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("could not write lock file: %w", err)
+	}
+	f.setObserved(raw)
+
+	return nil
+}
+
+// withLock runs the function holding the lock sidecar file.
+func (f *fileLock) withLock(ctx context.Context, fn func() error) error {
+	sidecar := f.path + ".lock"
+	for {
+		fd, err := os.OpenFile(sidecar, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_ = fd.Close()
+			defer os.Remove(sidecar)
+			return fn()
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("could not create lock sidecar file: %w", err)
+		}
+
+		// If the sidecar file has been abandoned, remove it.
+		if info, err := os.Stat(sidecar); err == nil && time.Since(info.ModTime()) > fileLockStaleTimeout {
+			_ = os.Remove(sidecar)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(fileLockRetryPeriod):
+		}
+	}
+}
+
+func (f *fileLock) setObserved(raw []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observed = raw
+}
+
+func (f *fileLock) getObserved() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.observed
+}
@@ -2,17 +2,17 @@ package leaderelection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
-	"k8s.io/client-go/tools/record"
 
 	"github.com/spotahome/kooper/v2/log"
 )
@@ -37,10 +37,121 @@ type LockConfig struct {
 	RetryPeriod time.Duration
 }
 
+// ErrLeadershipLost is returned by the runner when the leadership has been lost.
+var ErrLeadershipLost = errors.New("leadership lost")
+
 // Runner knows how to run using the leader election.
 type Runner interface {
-	// Run will run if the instance takes the lead. It's a blocking action.
-	Run(func() error) error
+	// Run will run if the instance takes the lead. It's a blocking action. The context received
+	// by the function will be cancelled when the leadership is lost or the context is cancelled.
+	Run(ctx context.Context, f func(ctx context.Context) error) error
+	// Leader returns the identity of the current leader, empty if the leader is not known yet.
+	Leader() string
+	// IsLeader returns true if the instance is the current leader.
+	IsLeader() bool
+}
+
+// Config is the leader election runner configuration.
+type Config struct {
+	// Key is the name of the lock, it identifies the different instances of the same controller.
+	Key string
+	// Lock is optional, if set it will be used as the lock backend instead of a Kubernetes Lease lock
+	// (e.g NewMemoryLock, NewFileLock or other Kubernetes `resourcelock.Interface` lock types). The
+	// Kubernetes lock options will be ignored.
+	Lock Lock
+	// Namespace is the namespace where the lock will be created.
+	Namespace string
+	// KubernetesClient is the Kubernetes client used to manage the lock.
+	KubernetesClient kubernetes.Interface
+	// LockConfig is the lock configuration, if nil it will use a safe configuration.
+	LockConfig *LockConfig
+	// LockLabels are the labels set on the lock resource when it's created.
+	LockLabels map[string]string
+	// Identity is the identity of the instance, it must be unique between the instances (e.g the pod
+	// name using the downward API). By default it will use the hostname with a random suffix. When using
+	// a custom Lock, the lock identity is used, if set, it must match the lock identity.
+	Identity string
+	// EventRecorder records the leader election events. By default the events will be sent to
+	// the lock namespace.
+	EventRecorder resourcelock.EventRecorder
+	// Logger will log messages of the leader election.
+	Logger log.Logger
+	// DisableReleaseOnCancel will not release the lock when the context is cancelled. By default the
+	// lock is released once the function has finished, so the other instances don't need to wait the
+	// lease duration to take the leadership.
+	DisableReleaseOnCancel bool
+	// ReenterElection will make the runner enter the election again when the leadership is lost,
+	// instead of returning ErrLeadershipLost.
+	ReenterElection bool
+	// MetricsRecorder will record the leader election metrics.
+	MetricsRecorder MetricsRecorder
+	// OnStartedLeading is an optional callback called when the instance starts leading.
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading is an optional callback called when the instance stops leading.
+	OnStoppedLeading func()
+	// OnNewLeader is an optional callback called when a new leader is observed.
+	OnNewLeader func(identity string)
+}
+
+func (c *Config) setDefaults() error {
+	// Key required
+	if c.Key == "" {
+		return fmt.Errorf("running in leader election mode requires a key for identification the different instances")
+	}
+
+	if c.Lock != nil {
+		if c.Identity != "" && c.Identity != c.Lock.Identity() {
+			return fmt.Errorf("identity %q doesn't match the lock identity %q", c.Identity, c.Lock.Identity())
+		}
+		c.Identity = c.Lock.Identity()
+	} else if err := c.setKubernetesLockDefaults(); err != nil {
+		return err
+	}
+
+	// If lock configuration is nil then fallback to defaults.
+	if c.LockConfig == nil {
+		c.LockConfig = &LockConfig{
+			LeaseDuration: defLeaseDuration,
+			RenewDeadline: defRenewDeadline,
+			RetryPeriod:   defRetryPeriod,
+		}
+	}
+
+	if c.MetricsRecorder == nil {
+		c.MetricsRecorder = DummyMetricsRecorder
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.NewStd(false)
+		c.Logger.Warningf("no logger specified, fallback to default logger, to disable logging use a explicit Noop logger")
+	}
+	c.Logger = c.Logger.WithKV(log.KV{
+		"source-service":     "kooper/leader-election",
+		"leader-election-id": fmt.Sprintf("%s/%s", c.Namespace, c.Key),
+	})
+
+	return nil
+}
+
+func (c *Config) setKubernetesLockDefaults() error {
+	// Error if no namespace set.
+	if c.Namespace == "" {
+		return fmt.Errorf("running in leader election mode requires the namespace running")
+	}
+
+	if c.KubernetesClient == nil {
+		return fmt.Errorf("running in leader election mode requires a Kubernetes client")
+	}
+
+	if c.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("could not get hostname for the identity: %w", err)
+		}
+		c.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+
+	return nil
 }
 
 // runner is the leader election default implementation.
@@ -50,7 +161,13 @@ type runner struct {
 	k8scli       kubernetes.Interface
 	lockCfg      *LockConfig
 	resourceLock resourcelock.Interface
+	events       *kubernetesEventRecorder
+	cfg          Config
 	logger       log.Logger
+
+	mu       sync.Mutex
+	leader   string
+	isLeader bool
 }
 
 // NewDefault returns a new leader election service with a safe lock configuration.
@@ -60,28 +177,28 @@ func NewDefault(key, namespace string, k8scli kubernetes.Interface, logger log.L
 
 // New returns a new leader election service.
 func New(key, namespace string, lockCfg *LockConfig, k8scli kubernetes.Interface, logger log.Logger) (Runner, error) {
-	// If lock configuration is nil then fallback to defaults.
-	if lockCfg == nil {
-		lockCfg = &LockConfig{
-			LeaseDuration: defLeaseDuration,
-			RenewDeadline: defRenewDeadline,
-			RetryPeriod:   defRetryPeriod,
-		}
-	}
+	return NewWithConfig(Config{
+		Key:              key,
+		Namespace:        namespace,
+		KubernetesClient: k8scli,
+		LockConfig:       lockCfg,
+		Logger:           logger,
+	})
+}
 
-	r := &runner{
-		lockCfg:   lockCfg,
-		key:       key,
-		namespace: namespace,
-		k8scli:    k8scli,
-		logger: logger.WithKV(log.KV{
-			"source-service":     "kooper/leader-election",
-			"leader-election-id": fmt.Sprintf("%s/%s", namespace, key),
-		}),
+// NewWithConfig returns a new leader election service using a configuration.
+func NewWithConfig(cfg Config) (Runner, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	if err := r.validate(); err != nil {
-		return nil, err
+	r := &runner{
+		lockCfg:   cfg.LockConfig,
+		key:       cfg.Key,
+		namespace: cfg.Namespace,
+		k8scli:    cfg.KubernetesClient,
+		cfg:       cfg,
+		logger:    cfg.Logger,
 	}
 
 	if err := r.initResourceLock(); err != nil {
@@ -91,29 +208,20 @@ func New(key, namespace string, lockCfg *LockConfig, k8scli kubernetes.Interface
 	return r, nil
 }
 
-func (r *runner) validate() error {
-	// Error if no namespace set.
-	if r.namespace == "" {
-		return fmt.Errorf("running in leader election mode requires the namespace running")
-	}
-	// Key required
-	if r.key == "" {
-		return fmt.Errorf("running in leader election mode requires a key for identification the different instances")
-	}
-	return nil
-}
-
 func (r *runner) initResourceLock() error {
-	// Create the lock resource for the leader election.
-	hostname, err := os.Hostname()
-	if err != nil {
-		return err
+	if r.cfg.Lock != nil {
+		r.resourceLock = newMeasuredLock(r.lockName(), r.cfg.MetricsRecorder, r.cfg.Lock)
+		return nil
 	}
-	id := hostname + "_" + string(uuid.NewUUID())
 
-	eventBroadcaster := record.NewBroadcaster()
-	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: r.key, Host: id})
+	// If we don't have a recorder, create one that sends the events to the lock namespace.
+	recorder := r.cfg.EventRecorder
+	if recorder == nil {
+		r.events = newKubernetesEventRecorder(r.k8scli, r.namespace, v1.EventSource{Component: r.key, Host: r.cfg.Identity})
+		recorder = r.events
+	}
 
+	// Create the lock resource for the leader election.
 	rl, err := resourcelock.New(
 		resourcelock.LeasesResourceLock,
 		r.namespace,
@@ -121,7 +229,7 @@ func (r *runner) initResourceLock() error {
 		r.k8scli.CoreV1(),
 		r.k8scli.CoordinationV1(),
 		resourcelock.ResourceLockConfig{
-			Identity:      id,
+			Identity:      r.cfg.Identity,
 			EventRecorder: recorder,
 		},
 	)
@@ -129,36 +237,141 @@ func (r *runner) initResourceLock() error {
 		return fmt.Errorf("error creating lock: %v", err)
 	}
 
-	r.resourceLock = rl
+	if ll, ok := rl.(*resourcelock.LeaseLock); ok && len(r.cfg.LockLabels) > 0 {
+		rl = newLabeledLeaseLock(ll, r.cfg.LockLabels)
+	}
+
+	r.resourceLock = newMeasuredLock(r.lockName(), r.cfg.MetricsRecorder, rl)
 	return nil
+}
+
+func (r *runner) lockName() string {
+	if r.namespace == "" {
+		return r.key
+	}
+	return fmt.Sprintf("%s/%s", r.namespace, r.key)
+}
+
+func (r *runner) Leader() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.leader
+}
+
+func (r *runner) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.isLeader
+}
+
+func (r *runner) setLeader(identity string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leader = identity
+}
 
+func (r *runner) setIsLeader(isLeader bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.isLeader = isLeader
+	// We are not the leader anymore and we don't know the new one yet.
+	if !isLeader && r.leader == r.resourceLock.Identity() {
+		r.leader = ""
+	}
+}
+
+func (r *runner) Run(ctx context.Context, f func(ctx context.Context) error) error {
+	// Send the events to Kubernetes while running.
+	if r.events != nil {
+		stop := r.events.start()
+		defer stop()
+	}
+
+	for {
+		err := r.run(ctx, f)
+		if !errors.Is(err, ErrLeadershipLost) || !r.cfg.ReenterElection || ctx.Err() != nil {
+			return err
+		}
+		r.logger.Warningf("leadership lost, entering the leader election again...")
+	}
 }
 
-func (r *runner) Run(f func() error) error {
-	errC := make(chan error, 1) // Channel to get the function returning error.
+func (r *runner) run(ctx context.Context, f func(ctx context.Context) error) error {
+	// The leader elector has its own context, this way on cancellation we can wait until the
+	// function finishes before stopping the leader elector (and releasing the lock).
+	leCtx, leCancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer leCancel()
+
+	var (
+		mu       sync.Mutex
+		stopped  bool
+		leading  bool
+		fResultC = make(chan error, 1)
+		leDoneC  = make(chan struct{})
+	)
+
+	// stop marks the execution as stopped so the function is not started, returns if the function is running.
+	stop := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		return leading
+	}
 
 	// The function to execute when leader acquired.
-	lef := func(ctx context.Context) {
+	lef := func(leadCtx context.Context) {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		leading = true
+		mu.Unlock()
+
 		r.logger.Infof("lead acquire, starting...")
-		// Wait until f finishes or leader elector runner stops.
-		select {
-		case <-ctx.Done():
-			errC <- nil
-		case errC <- f():
+		r.setIsLeader(true)
+		r.cfg.MetricsRecorder.SetLeader(ctx, r.lockName(), true)
+		if r.cfg.OnStartedLeading != nil {
+			go r.cfg.OnStartedLeading(leadCtx)
 		}
+
+		// The function will be stopped when the leadership is lost or the context cancelled.
+		fCtx, fCancel := context.WithCancel(ctx)
+		defer fCancel()
+		stopAfter := context.AfterFunc(leadCtx, fCancel)
+		defer stopAfter()
+
+		err := f(fCtx)
+		if leadCtx.Err() != nil && ctx.Err() == nil {
+			err = ErrLeadershipLost
+		}
+		r.setIsLeader(false)
+		r.cfg.MetricsRecorder.SetLeader(ctx, r.lockName(), false)
+		if r.cfg.OnStoppedLeading != nil {
+			r.cfg.OnStoppedLeading()
+		}
+		fResultC <- err
+
 		r.logger.Infof("lead execution stopped")
 	}
 
 	// Create the leader election configuration
 	lec := leaderelection.LeaderElectionConfig{
-		Lock:          r.resourceLock,
-		LeaseDuration: r.lockCfg.LeaseDuration,
-		RenewDeadline: r.lockCfg.RenewDeadline,
-		RetryPeriod:   r.lockCfg.RetryPeriod,
+		Lock:            r.resourceLock,
+		LeaseDuration:   r.lockCfg.LeaseDuration,
+		RenewDeadline:   r.lockCfg.RenewDeadline,
+		RetryPeriod:     r.lockCfg.RetryPeriod,
+		ReleaseOnCancel: !r.cfg.DisableReleaseOnCancel,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: lef,
-			OnStoppedLeading: func() {
-				errC <- fmt.Errorf("leadership lost")
+			OnStoppedLeading: func() {},
+			OnNewLeader: func(identity string) {
+				r.logger.Infof("new leader observed: %s", identity)
+				r.setLeader(identity)
+				r.cfg.MetricsRecorder.IncLeaderTransitions(ctx, r.lockName())
+				if r.cfg.OnNewLeader != nil {
+					r.cfg.OnNewLeader(identity)
+				}
 			},
 		},
 	}
@@ -171,9 +384,34 @@ func (r *runner) Run(f func() error) error {
 
 	// Execute!
 	r.logger.Infof("running in leader election mode, waiting to acquire leadership...")
-	go le.Run(context.TODO())
+	go func() {
+		defer close(leDoneC)
+		le.Run(leCtx)
+	}()
 
 	// Wait until stopping the execution returns the result.
-	err = <-errC
-	return err
+	select {
+	// The function finished by itself or because the leadership has been lost.
+	case err := <-fResultC:
+		leCancel()
+		<-leDoneC
+		return err
+
+	// The leader elector stopped by itself, this means that the leadership has been lost.
+	case <-leDoneC:
+		if stop() {
+			return <-fResultC
+		}
+		return ErrLeadershipLost
+
+	// The execution has been cancelled, wait for the function before releasing the lock.
+	case <-ctx.Done():
+		var err error
+		if stop() {
+			err = <-fResultC
+		}
+		leCancel()
+		<-leDoneC
+		return err
+	}
 }
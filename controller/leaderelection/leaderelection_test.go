@@ -0,0 +1,327 @@
+package leaderelection_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/spotahome/kooper/v2/controller/leaderelection"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+const (
+	testKey       = "test"
+	testNamespace = "default"
+)
+
+var testLockConfig = &leaderelection.LockConfig{
+	LeaseDuration: 150 * time.Millisecond,
+	RenewDeadline: 100 * time.Millisecond,
+	RetryPeriod:   10 * time.Millisecond,
+}
+
+func leaseHolder(t *testing.T, cli kubernetes.Interface) string {
+	t.Helper()
+	lease, err := cli.CoordinationV1().Leases(testNamespace).Get(context.Background(), testKey, metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// stealLease will set another holder on the lease.
+func stealLease(t *testing.T, cli kubernetes.Interface, leaseDurationSeconds int32) {
+	t.Helper()
+	lease, err := cli.CoordinationV1().Leases(testNamespace).Get(context.Background(), testKey, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	now := metav1.NewMicroTime(time.Now())
+	holder := "other"
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	_, err = cli.CoordinationV1().Leases(testNamespace).Update(context.Background(), lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func TestRunnerRun(t *testing.T) {
+	tests := map[string]struct {
+		cfg    leaderelection.Config
+		run    func(t *testing.T, cli kubernetes.Interface, cancel func(), started func() int32)
+		expErr error
+		expRun func(t *testing.T, cli kubernetes.Interface, started int32)
+	}{
+		"Cancelling the context should stop the function and release the lock.": {
+			run: func(t *testing.T, cli kubernetes.Interface, cancel func(), started func() int32) {
+				require.Eventually(t, func() bool { return started() == 1 }, time.Second, time.Millisecond)
+				cancel()
+			},
+			expRun: func(t *testing.T, cli kubernetes.Interface, started int32) {
+				assert.Equal(t, "", leaseHolder(t, cli))
+			},
+		},
+
+		"Cancelling the context with release on cancel disabled should stop the function and keep the lock.": {
+			cfg: leaderelection.Config{DisableReleaseOnCancel: true},
+			run: func(t *testing.T, cli kubernetes.Interface, cancel func(), started func() int32) {
+				require.Eventually(t, func() bool { return started() == 1 }, time.Second, time.Millisecond)
+				cancel()
+			},
+			expRun: func(t *testing.T, cli kubernetes.Interface, started int32) {
+				assert.NotEqual(t, "", leaseHolder(t, cli))
+			},
+		},
+
+		"Losing the leadership should stop the function and return an error.": {
+			run: func(t *testing.T, cli kubernetes.Interface, cancel func(), started func() int32) {
+				require.Eventually(t, func() bool { return started() == 1 }, time.Second, time.Millisecond)
+				stealLease(t, cli, 9999)
+			},
+			expErr: leaderelection.ErrLeadershipLost,
+			expRun: func(t *testing.T, cli kubernetes.Interface, started int32) {
+				assert.Equal(t, int32(1), started)
+				assert.Equal(t, "other", leaseHolder(t, cli))
+			},
+		},
+
+		"Losing the leadership with reenter election should run the function again when the leadership is acquired.": {
+			cfg: leaderelection.Config{ReenterElection: true},
+			run: func(t *testing.T, cli kubernetes.Interface, cancel func(), started func() int32) {
+				require.Eventually(t, func() bool { return started() == 1 }, time.Second, time.Millisecond)
+				stealLease(t, cli, 1)
+				require.Eventually(t, func() bool { return started() == 2 }, 3*time.Second, time.Millisecond)
+				cancel()
+			},
+			expRun: func(t *testing.T, cli kubernetes.Interface, started int32) {
+				assert.Equal(t, int32(2), started)
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cli := fake.NewSimpleClientset()
+
+			cfg := test.cfg
+			cfg.Key = testKey
+			cfg.Namespace = testNamespace
+			cfg.KubernetesClient = cli
+			cfg.LockConfig = testLockConfig
+			cfg.Logger = log.Dummy
+			r, err := leaderelection.NewWithConfig(cfg)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var started atomic.Int32
+			errC := make(chan error, 1)
+			go func() {
+				errC <- r.Run(ctx, func(ctx context.Context) error {
+					started.Add(1)
+					<-ctx.Done()
+					return nil
+				})
+			}()
+
+			test.run(t, cli, cancel, started.Load)
+
+			select {
+			case err := <-errC:
+				assert.ErrorIs(t, err, test.expErr)
+				test.expRun(t, cli, started.Load())
+			case <-time.After(3 * time.Second):
+				assert.Fail(t, "timeout waiting for the runner to stop")
+			}
+		})
+	}
+}
+
+type testMetricsRecorder struct {
+	mu          sync.Mutex
+	isLeader    bool
+	transitions int
+	renewals    int
+}
+
+func (t *testMetricsRecorder) SetLeader(_ context.Context, _ string, isLeader bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.isLeader = isLeader
+}
+
+func (t *testMetricsRecorder) IncLeaderTransitions(context.Context, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transitions++
+}
+
+func (t *testMetricsRecorder) ObserveLeaderRenewDuration(context.Context, string, bool, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.renewals++
+}
+
+func (t *testMetricsRecorder) state() (isLeader bool, transitions, renewals int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.isLeader, t.transitions, t.renewals
+}
+
+func TestRunnerObservability(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	cli := fake.NewSimpleClientset()
+	mrec := &testMetricsRecorder{}
+	var started, stopped atomic.Bool
+	newLeaderC := make(chan string, 10)
+
+	r, err := leaderelection.NewWithConfig(leaderelection.Config{
+		Key:              testKey,
+		Namespace:        testNamespace,
+		KubernetesClient: cli,
+		LockConfig:       testLockConfig,
+		Logger:           log.Dummy,
+		MetricsRecorder:  mrec,
+		OnStartedLeading: func(context.Context) { started.Store(true) },
+		OnStoppedLeading: func() { stopped.Store(true) },
+		OnNewLeader:      func(identity string) { newLeaderC <- identity },
+	})
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errC := make(chan error, 1)
+	go func() {
+		errC <- r.Run(ctx, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	// Check the leading state.
+	require.Eventually(func() bool { return r.IsLeader() && started.Load() }, time.Second, time.Millisecond)
+	select {
+	case identity := <-newLeaderC:
+		assert.Equal(leaseHolder(t, cli), identity)
+		assert.Equal(identity, r.Leader())
+	case <-time.After(time.Second):
+		assert.Fail("new leader callback not called")
+	}
+	require.Eventually(func() bool { _, _, renewals := mrec.state(); return renewals > 1 }, time.Second, time.Millisecond)
+	isLeader, transitions, _ := mrec.state()
+	assert.True(isLeader)
+	assert.Equal(1, transitions)
+	assert.False(stopped.Load())
+
+	// Check the stopped state.
+	cancel()
+	require.NoError(<-errC)
+	isLeader, _, _ = mrec.state()
+	assert.False(isLeader)
+	assert.False(r.IsLeader())
+	assert.True(stopped.Load())
+}
+
+func TestNewWithConfig(t *testing.T) {
+	tests := map[string]struct {
+		cfg    leaderelection.Config
+		expErr bool
+	}{
+		"A valid configuration should not fail.": {
+			cfg: leaderelection.Config{Key: testKey, Namespace: testNamespace, KubernetesClient: fake.NewSimpleClientset()},
+		},
+
+		"A missing namespace should fail.": {
+			cfg:    leaderelection.Config{Key: testKey, KubernetesClient: fake.NewSimpleClientset()},
+			expErr: true,
+		},
+
+		"A missing key should fail.": {
+			cfg:    leaderelection.Config{Namespace: testNamespace, KubernetesClient: fake.NewSimpleClientset()},
+			expErr: true,
+		},
+
+		"A custom lock without namespace and Kubernetes client should not fail.": {
+			cfg: leaderelection.Config{Key: testKey, Lock: leaderelection.NewMemoryLock(leaderelection.NewMemoryLockStore("test"), "c1")},
+		},
+
+		"A custom lock with the same identity should not fail.": {
+			cfg: leaderelection.Config{Key: testKey, Identity: "c1", Lock: leaderelection.NewMemoryLock(leaderelection.NewMemoryLockStore("test"), "c1")},
+		},
+
+		"A custom lock with a different identity should fail.": {
+			cfg:    leaderelection.Config{Key: testKey, Identity: "c2", Lock: leaderelection.NewMemoryLock(leaderelection.NewMemoryLockStore("test"), "c1")},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := test.cfg
+			cfg.Logger = log.Dummy
+			_, err := leaderelection.NewWithConfig(cfg)
+
+			if test.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRunnerLockOptions(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	cli := fake.NewSimpleClientset()
+	recorder := record.NewFakeRecorder(10)
+	r, err := leaderelection.NewWithConfig(leaderelection.Config{
+		Key:              testKey,
+		Namespace:        testNamespace,
+		KubernetesClient: cli,
+		LockConfig:       testLockConfig,
+		Logger:           log.Dummy,
+		Identity:         "test-pod-0",
+		LockLabels:       map[string]string{"app": "test"},
+		EventRecorder:    recorder,
+	})
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = r.Run(ctx, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+	}()
+
+	require.Eventually(r.IsLeader, time.Second, time.Millisecond)
+	// The lock should be renewed.
+	assert.Never(func() bool { return !r.IsLeader() }, 300*time.Millisecond, 10*time.Millisecond)
+
+	lease, err := cli.CoordinationV1().Leases(testNamespace).Get(context.Background(), testKey, metav1.GetOptions{})
+	require.NoError(err)
+	assert.Equal("test-pod-0", *lease.Spec.HolderIdentity)
+	assert.Equal(map[string]string{"app": "test"}, lease.Labels)
+
+	select {
+	case ev := <-recorder.Events:
+		assert.Contains(ev, "test-pod-0 became leader")
+	case <-time.After(time.Second):
+		assert.Fail("leader election event not recorded")
+	}
+}
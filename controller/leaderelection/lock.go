@@ -0,0 +1,85 @@
+package leaderelection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Lock is the leader election lock backend. It has the same methods as the Kubernetes client
+// resourcelock.Interface, so the Kubernetes locks can be used as a Lock and vice versa.
+type Lock interface {
+	// Get returns the lock record, if the lock doesn't exist it will return a Kubernetes not found error.
+	Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error)
+	// Create creates the lock with the record.
+	Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error
+	// Update updates the lock record, it will fail if the lock has changed since it was obtained.
+	Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error
+	// RecordEvent records an event of the lock.
+	RecordEvent(string)
+	// Identity returns the identity of the lock candidate.
+	Identity() string
+	// Describe returns a description of the lock.
+	Describe() string
+}
+
+var _ Lock = resourcelock.Interface(nil)
+var _ resourcelock.Interface = Lock(nil)
+
+// labeledLeaseLock is a Lease lock that sets labels on the Lease when it's created.
+type labeledLeaseLock struct {
+	*resourcelock.LeaseLock
+	labels map[string]string
+}
+
+func newLabeledLeaseLock(ll *resourcelock.LeaseLock, labels map[string]string) resourcelock.Interface {
+	return &labeledLeaseLock{
+		LeaseLock: ll,
+		labels:    labels,
+	}
+}
+
+func (l *labeledLeaseLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	_, err := l.Client.Leases(l.LeaseMeta.Namespace).Create(ctx, &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      l.LeaseMeta.Name,
+			Namespace: l.LeaseMeta.Namespace,
+			Labels:    l.labels,
+		},
+		Spec: resourcelock.LeaderElectionRecordToLeaseSpec(&ler),
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	// Load the created Lease on the lock so it can be updated.
+	_, _, err = l.LeaseLock.Get(ctx)
+	return err
+}
+
+// lockData is the data stored by the Kooper locks. The record times have second precision, so
+// the version changes on every write to let the candidates observe the renewals.
+type lockData struct {
+	Version int64                             `json:"version"`
+	Record  resourcelock.LeaderElectionRecord `json:"record"`
+}
+
+func encodeLockData(version int64, ler resourcelock.LeaderElectionRecord) ([]byte, error) {
+	raw, err := json.Marshal(lockData{Version: version, Record: ler})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode lock data: %w", err)
+	}
+	return raw, nil
+}
+
+func decodeLockData(raw []byte) (*lockData, error) {
+	data := &lockData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, fmt.Errorf("could not decode lock data: %w", err)
+	}
+	return data, nil
+}
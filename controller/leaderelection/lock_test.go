@@ -0,0 +1,134 @@
+package leaderelection_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/spotahome/kooper/v2/controller/leaderelection"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+func TestLocks(t *testing.T) {
+	tests := map[string]struct {
+		newLocks func(t *testing.T) (leaderelection.Lock, leaderelection.Lock)
+	}{
+		"Memory lock.": {
+			newLocks: func(t *testing.T) (leaderelection.Lock, leaderelection.Lock) {
+				store := leaderelection.NewMemoryLockStore("test")
+				return leaderelection.NewMemoryLock(store, "c1"), leaderelection.NewMemoryLock(store, "c2")
+			},
+		},
+
+		"File lock.": {
+			newLocks: func(t *testing.T) (leaderelection.Lock, leaderelection.Lock) {
+				path := filepath.Join(t.TempDir(), "test.lock")
+				l1, err := leaderelection.NewFileLock(path, "c1")
+				require.NoError(t, err)
+				l2, err := leaderelection.NewFileLock(path, "c2")
+				require.NoError(t, err)
+				return l1, l2
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			ctx := context.Background()
+
+			l1, l2 := test.newLocks(t)
+			assert.Equal("c1", l1.Identity())
+			assert.Equal("c2", l2.Identity())
+
+			// Missing lock.
+			_, _, err := l1.Get(ctx)
+			assert.True(kubeerrors.IsNotFound(err))
+
+			// Create the lock.
+			require.NoError(l1.Create(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "c1"}))
+			err = l2.Create(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "c2"})
+			assert.True(kubeerrors.IsAlreadyExists(err))
+
+			ler, _, err := l2.Get(ctx)
+			require.NoError(err)
+			assert.Equal("c1", ler.HolderIdentity)
+
+			// Updating an outdated lock should fail.
+			require.NoError(l1.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "c1", LeaderTransitions: 1}))
+			err = l2.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "c2"})
+			assert.True(kubeerrors.IsConflict(err))
+
+			// Updating an updated lock should not fail.
+			_, _, err = l2.Get(ctx)
+			require.NoError(err)
+			require.NoError(l2.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "c2", LeaderTransitions: 2}))
+
+			ler, _, err = l1.Get(ctx)
+			require.NoError(err)
+			assert.Equal("c2", ler.HolderIdentity)
+			assert.Equal(2, ler.LeaderTransitions)
+		})
+	}
+}
+
+// failoverLockConfig is a lock configuration with the minimum lease duration (1s) that allows the
+// candidates to detect a valid leader.
+var failoverLockConfig = &leaderelection.LockConfig{
+	LeaseDuration: 1 * time.Second,
+	RenewDeadline: 500 * time.Millisecond,
+	RetryPeriod:   20 * time.Millisecond,
+}
+
+func TestRunnerFailoverWithMemoryLock(t *testing.T) {
+	require := require.New(t)
+
+	store := leaderelection.NewMemoryLockStore("test")
+	newRunner := func(id string) leaderelection.Runner {
+		r, err := leaderelection.NewWithConfig(leaderelection.Config{
+			Key:        testKey,
+			Lock:       leaderelection.NewMemoryLock(store, id),
+			LockConfig: failoverLockConfig,
+			Logger:     log.Dummy,
+		})
+		require.NoError(err)
+		return r
+	}
+
+	run := func(r leaderelection.Runner) (context.CancelFunc, chan error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		errC := make(chan error, 1)
+		go func() {
+			errC <- r.Run(ctx, func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+		}()
+		return cancel, errC
+	}
+
+	r1, r2 := newRunner("c1"), newRunner("c2")
+	cancel1, errC1 := run(r1)
+	defer cancel1()
+	require.Eventually(r1.IsLeader, time.Second, time.Millisecond)
+
+	cancel2, errC2 := run(r2)
+	defer cancel2()
+	require.Never(r2.IsLeader, 200*time.Millisecond, 10*time.Millisecond)
+	require.Equal("c1", r2.Leader())
+
+	// Stop the leader, the other one should take the leadership.
+	cancel1()
+	require.NoError(<-errC1)
+	require.Eventually(r2.IsLeader, time.Second, time.Millisecond)
+
+	cancel2()
+	require.NoError(<-errC2)
+}
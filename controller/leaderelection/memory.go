@@ -0,0 +1,121 @@
+package leaderelection
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var memoryLockResource = schema.GroupResource{Group: "kooper.spotahome.com", Resource: "memorylocks"}
+
+// MemoryLockStore stores the record of an in-memory lock, all the candidates of the same
+// lock must use the same store.
+type MemoryLockStore struct {
+	name string
+	mu   sync.Mutex
+	raw  []byte
+}
+
+// NewMemoryLockStore returns a new in-memory lock store.
+func NewMemoryLockStore(name string) *MemoryLockStore {
+	return &MemoryLockStore{name: name}
+}
+
+// memoryLock is a lock that stores the record in memory, useful for tests.
+type memoryLock struct {
+	store    *MemoryLockStore
+	identity string
+
+	mu       sync.Mutex
+	observed []byte
+}
+
+// NewMemoryLock returns a lock for the identity candidate that stores the record in memory. The lock
+// only works between candidates on the same process, it's useful to test the leader election (e.g
+// controllers failover) without a Kubernetes cluster.
+func NewMemoryLock(store *MemoryLockStore, identity string) Lock {
+	return &memoryLock{
+		store:    store,
+		identity: identity,
+	}
+}
+
+func (m *memoryLock) Get(_ context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	m.store.mu.Lock()
+	raw := m.store.raw
+	m.store.mu.Unlock()
+
+	if raw == nil {
+		return nil, nil, kubeerrors.NewNotFound(memoryLockResource, m.store.name)
+	}
+
+	data, err := decodeLockData(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.setObserved(raw)
+
+	return &data.Record, raw, nil
+}
+
+func (m *memoryLock) Create(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	raw, err := encodeLockData(1, ler)
+	if err != nil {
+		return err
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if m.store.raw != nil {
+		return kubeerrors.NewAlreadyExists(memoryLockResource, m.store.name)
+	}
+	m.store.raw = raw
+	m.setObserved(raw)
+
+	return nil
+}
+
+func (m *memoryLock) Update(_ context.Context, ler resourcelock.LeaderElectionRecord) error {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if m.store.raw == nil {
+		return kubeerrors.NewNotFound(memoryLockResource, m.store.name)
+	}
+	if !bytes.Equal(m.store.raw, m.getObserved()) {
+		return kubeerrors.NewConflict(memoryLockResource, m.store.name, fmt.Errorf("the lock has been modified"))
+	}
+
+	current, err := decodeLockData(m.store.raw)
+	if err != nil {
+		return err
+	}
+	raw, err := encodeLockData(current.Version+1, ler)
+	if err != nil {
+		return err
+	}
+	m.store.raw = raw
+	m.setObserved(raw)
+
+	return nil
+}
+
+func (m *memoryLock) setObserved(raw []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observed = raw
+}
+
+func (m *memoryLock) getObserved() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.observed
+}
+
+func (m *memoryLock) RecordEvent(string) {}
+func (m *memoryLock) Identity() string   { return m.identity }
+func (m *memoryLock) Describe() string   { return "memory/" + m.store.name }
@@ -0,0 +1,61 @@
+package leaderelection
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// MetricsRecorder knows how to record the leader election metrics.
+type MetricsRecorder interface {
+	// SetLeader sets if the instance is the leader of the lock.
+	SetLeader(ctx context.Context, lock string, isLeader bool)
+	// IncLeaderTransitions increments the number of observed leadership transitions of the lock.
+	IncLeaderTransitions(ctx context.Context, lock string)
+	// ObserveLeaderRenewDuration measures the duration of the lock acquisitions and renewals.
+	ObserveLeaderRenewDuration(ctx context.Context, lock string, success bool, startAt time.Time)
+}
+
+// DummyMetricsRecorder is a dummy metrics recorder.
+var DummyMetricsRecorder = dummy(0)
+var _ MetricsRecorder = DummyMetricsRecorder
+
+type dummy int
+
+func (dummy) SetLeader(context.Context, string, bool)                             {}
+func (dummy) IncLeaderTransitions(context.Context, string)                        {}
+func (dummy) ObserveLeaderRenewDuration(context.Context, string, bool, time.Time) {}
+
+// measuredLock wraps a lock and measures the acquisitions and renewals of the lock by this instance.
+type measuredLock struct {
+	resourcelock.Interface
+	name string
+	mrec MetricsRecorder
+}
+
+func newMeasuredLock(name string, mrec MetricsRecorder, next resourcelock.Interface) resourcelock.Interface {
+	return &measuredLock{
+		Interface: next,
+		name:      name,
+		mrec:      mrec,
+	}
+}
+
+func (m *measuredLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) (err error) {
+	if ler.HolderIdentity == m.Identity() {
+		defer func(t0 time.Time) {
+			m.mrec.ObserveLeaderRenewDuration(ctx, m.name, err == nil, t0)
+		}(time.Now())
+	}
+	return m.Interface.Create(ctx, ler)
+}
+
+func (m *measuredLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) (err error) {
+	if ler.HolderIdentity == m.Identity() {
+		defer func(t0 time.Time) {
+			m.mrec.ObserveLeaderRenewDuration(ctx, m.name, err == nil, t0)
+		}(time.Now())
+	}
+	return m.Interface.Update(ctx, ler)
+}
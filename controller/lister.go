@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// OwnerUIDIndex is the name of the index that indexes objects by their owner references UIDs.
+// It can be set on the controller using `OwnerUIDIndexFunc`.
+const OwnerUIDIndex = "kooper.spotahome.com/owner-uid"
+
+// OwnerUIDIndexFunc is an index func that indexes the objects by their owner references UIDs.
+func OwnerUIDIndexFunc(obj interface{}) ([]string, error) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, fmt.Errorf("object has no meta: %w", err)
+	}
+
+	uids := make([]string, 0, len(m.GetOwnerReferences()))
+	for _, or := range m.GetOwnerReferences() {
+		uids = append(uids, string(or.UID))
+	}
+
+	return uids, nil
+}
+
+// Lister knows how to get objects from the controller local cache without calling the API server.
+//
+// The returned objects are shared with the controller cache, they must be treated as read-only,
+// if they need to be mutated, use a copy (e.g `DeepCopyObject`).
+type Lister interface {
+	// Get returns the object by its namespace and name, the namespace is empty on cluster scoped objects.
+	Get(namespace, name string) (obj runtime.Object, exists bool, err error)
+	// List returns all the objects that match the selector.
+	List(selector labels.Selector) ([]runtime.Object, error)
+	// ListNamespace returns all the objects of a namespace that match the selector.
+	ListNamespace(namespace string, selector labels.Selector) ([]runtime.Object, error)
+	// ByIndex returns the objects that match the indexed value on the index (e.g `OwnerUIDIndex`).
+	ByIndex(indexName, indexedValue string) ([]runtime.Object, error)
+}
+
+type indexerLister struct {
+	indexer cache.Indexer
+}
+
+func newIndexerLister(indexer cache.Indexer) Lister {
+	return indexerLister{indexer: indexer}
+}
+
+func (i indexerLister) Get(namespace, name string) (runtime.Object, bool, error) {
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+
+	obj, exists, err := i.indexer.GetByKey(key)
+	if err != nil || !exists {
+		return nil, exists, err
+	}
+
+	return obj.(runtime.Object), true, nil
+}
+
+func (i indexerLister) List(selector labels.Selector) ([]runtime.Object, error) {
+	objs := []runtime.Object{}
+	err := cache.ListAll(i.indexer, selector, func(obj interface{}) {
+		objs = append(objs, obj.(runtime.Object))
+	})
+	return objs, err
+}
+
+func (i indexerLister) ListNamespace(namespace string, selector labels.Selector) ([]runtime.Object, error) {
+	objs := []runtime.Object{}
+	err := cache.ListAllByNamespace(i.indexer, namespace, selector, func(obj interface{}) {
+		objs = append(objs, obj.(runtime.Object))
+	})
+	return objs, err
+}
+
+func (i indexerLister) ByIndex(indexName, indexedValue string) ([]runtime.Object, error) {
+	items, err := i.indexer.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		objs = append(objs, item.(runtime.Object))
+	}
+
+	return objs, nil
+}
+
+type contextKey int
+
+const listerContextKey contextKey = iota
+
+func contextWithLister(ctx context.Context, l Lister) context.Context {
+	return context.WithValue(ctx, listerContextKey, l)
+}
+
+// ListerFromContext returns the Lister of the controller local cache, the controller sets it
+// on the context received by the handlers. If the context doesn't have a Lister it will return nil.
+func ListerFromContext(ctx context.Context) Lister {
+	l, _ := ctx.Value(listerContextKey).(Lister)
+	return l
+}
@@ -9,11 +9,22 @@ import (
 type MetricsRecorder interface {
 	// IncResourceEvent increments in one the metric records of a queued event.
 	IncResourceEventQueued(ctx context.Context, controller string, isRequeue bool)
+	// IncResourceEventFiltered increments in one the metric records of an event that has not been queued
+	// because it has been filtered by the controller predicates.
+	IncResourceEventFiltered(ctx context.Context, controller string)
 	// ObserveResourceInQueueDuration measures how long takes to dequeue a queued object. If the object is already in queue
 	// it will be measured once, since the first time it was added to the queue.
 	ObserveResourceInQueueDuration(ctx context.Context, controller string, queuedAt time.Time)
 	// ObserveResourceProcessingDuration measures how long it takes to process a resources (handling).
 	ObserveResourceProcessingDuration(ctx context.Context, controller string, success bool, startProcessingAt time.Time)
+	// IncResourceProcessingTimeout increments in one the metric records of a resource processing (handling) that
+	// has been cancelled because it reached the handling timeout.
+	IncResourceProcessingTimeout(ctx context.Context, controller string)
+	// IncResourceProcessingPanic increments in one the metric records of a resource processing (handling) that
+	// has panicked.
+	IncResourceProcessingPanic(ctx context.Context, controller string)
+	// ObserveRetrieverOperationDuration measures how long it takes to a retriever to do an operation (list or watch).
+	ObserveRetrieverOperationDuration(ctx context.Context, retriever string, operation string, success bool, startAt time.Time)
 	// RegisterResourceQueueLengthFunc will register a function that will be called
 	// by the metrics recorder to get the length of a queue at a given point in time.
 	RegisterResourceQueueLengthFunc(controller string, f func(context.Context) int) error
@@ -25,9 +36,13 @@ var _ MetricsRecorder = DummyMetricsRecorder
 
 type dummy int
 
-func (dummy) IncResourceEventQueued(context.Context, string, bool)                       {}
-func (dummy) ObserveResourceInQueueDuration(context.Context, string, time.Time)          {}
-func (dummy) ObserveResourceProcessingDuration(context.Context, string, bool, time.Time) {}
+func (dummy) IncResourceEventQueued(context.Context, string, bool)                               {}
+func (dummy) IncResourceEventFiltered(context.Context, string)                                   {}
+func (dummy) ObserveResourceInQueueDuration(context.Context, string, time.Time)                  {}
+func (dummy) ObserveResourceProcessingDuration(context.Context, string, bool, time.Time)         {}
+func (dummy) IncResourceProcessingTimeout(context.Context, string)                               {}
+func (dummy) IncResourceProcessingPanic(context.Context, string)                                 {}
+func (dummy) ObserveRetrieverOperationDuration(context.Context, string, string, bool, time.Time) {}
 func (dummy) RegisterResourceQueueLengthFunc(controller string, f func(context.Context) int) error {
 	return nil
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+type contextKey int
+
+const dryRunContextKey contextKey = iota
+
+// DryRun returns a middleware that marks the handling context as dry-run, the handlers should check
+// it using `IsDryRun` and don't apply changes (e.g use Kubernetes API dry-run option).
+func DryRun() Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			ctx = context.WithValue(ctx, dryRunContextKey, true)
+			return next.Handle(ctx, obj)
+		})
+	}
+}
+
+// IsDryRun returns true if the handling context has been marked as dry-run.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunContextKey).(bool)
+	return dryRun
+}
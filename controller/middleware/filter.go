@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// NamespaceFilter returns a middleware that only handles the objects of the received namespaces,
+// the rest of the objects are ignored.
+func NamespaceFilter(namespaces ...string) Middleware {
+	allowed := map[string]struct{}{}
+	for _, ns := range namespaces {
+		allowed[ns] = struct{}{}
+	}
+
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			m, err := meta.Accessor(obj)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := allowed[m.GetNamespace()]; !ok {
+				return nil
+			}
+
+			return next.Handle(ctx, obj)
+		})
+	}
+}
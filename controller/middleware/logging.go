@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+// Logging returns a middleware that logs the handlings with the object key and the
+// handling duration, successful handlings and requeue results are logged in debug level.
+func Logging(logger log.Logger) Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			logger := logger.WithKV(log.KV{"object-key": objectKey(obj)})
+			t0 := time.Now()
+
+			err := next.Handle(ctx, obj)
+
+			logger = logger.WithKV(log.KV{"duration": time.Since(t0).String()})
+			if controller.IsRequeue(err) {
+				logger.Debugf("object handled, requeued: %s", err)
+				return err
+			}
+			if err != nil {
+				logger.Errorf("object handling failed: %s", err)
+				return err
+			}
+			logger.Debugf("object handled")
+
+			return nil
+		})
+	}
+}
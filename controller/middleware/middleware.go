@@ -0,0 +1,53 @@
+// Package middleware contains controller.Handler middlewares (decorators) that can be chained
+// to extend the handlers with common functionality (e.g logging, panic recovery...).
+package middleware
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Middleware wraps a controller.Handler with extra functionality.
+type Middleware func(next controller.Handler) controller.Handler
+
+// Chain returns a Middleware that chains the received middlewares, the first middleware
+// will be the outermost one (the first one being executed).
+//
+//	h = middleware.Chain(middleware.Recover(logger), middleware.Logging(logger))(h)
+func Chain(mws ...Middleware) Middleware {
+	return func(next controller.Handler) controller.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// objectKey returns the object `{namespace}/{name}` key (or `{name}` on cluster scoped objects).
+func objectKey(obj runtime.Object) string {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return "unknown"
+	}
+
+	if m.GetNamespace() == "" {
+		return m.GetName()
+	}
+
+	return m.GetNamespace() + "/" + m.GetName()
+}
+
+// objectType returns the object GVK, the typed objects usually have an empty GVK (e.g the ones from
+// the informers), in that case the Go type will be returned (e.g `*v1.Pod`).
+func objectType(obj runtime.Object) string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Empty() {
+		return fmt.Sprintf("%T", obj)
+	}
+
+	return gvk.String()
+}
@@ -0,0 +1,230 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/middleware"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+func newPod(ns, name string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+}
+
+func recordMiddleware(id string, calls *[]string) middleware.Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			*calls = append(*calls, id)
+			return next.Handle(ctx, obj)
+		})
+	}
+}
+
+type testTracer struct {
+	name       string
+	attributes map[string]string
+	err        error
+}
+
+func (t *testTracer) StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func(err error)) {
+	t.name = name
+	t.attributes = attributes
+	return ctx, func(err error) { t.err = err }
+}
+
+func TestChain(t *testing.T) {
+	calls := []string{}
+	h := middleware.Chain(
+		recordMiddleware("mw1", &calls),
+		recordMiddleware("mw2", &calls),
+		recordMiddleware("mw3", &calls),
+	)(controller.HandlerFunc(func(_ context.Context, _ runtime.Object) error {
+		calls = append(calls, "handler")
+		return nil
+	}))
+
+	err := h.Handle(context.TODO(), newPod("test-ns", "test"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mw1", "mw2", "mw3", "handler"}, calls)
+}
+
+func TestMiddlewares(t *testing.T) {
+	errTest := errors.New("wanted error")
+
+	tests := map[string]struct {
+		middleware middleware.Middleware
+		handler    func(t *testing.T) controller.HandlerFunc
+		obj        runtime.Object
+		expCalled  bool
+		expErr     error
+	}{
+		"Recover should convert handler panics to errors.": {
+			middleware: middleware.Recover(log.Dummy),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { panic("wanted panic") }
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+			expErr:    controller.ErrHandlerPanic,
+		},
+
+		"Recover should return the handler result if it doesn't panic.": {
+			middleware: middleware.Recover(log.Dummy),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { return nil }
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+		},
+
+		"Logging should return the handler errors.": {
+			middleware: middleware.Logging(log.Dummy),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { return errTest }
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+			expErr:    errTest,
+		},
+
+		"Logging should return the handler requeue results.": {
+			middleware: middleware.Logging(log.Dummy),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { return controller.RequeueAfter(time.Second) }
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+			expErr:    controller.RequeueAfter(time.Second),
+		},
+
+		"Timeout should set a deadline on the handler context.": {
+			middleware: middleware.Timeout(time.Minute),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(ctx context.Context, _ runtime.Object) error {
+					_, ok := ctx.Deadline()
+					assert.True(t, ok)
+					return nil
+				}
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+		},
+
+		"NamespaceFilter should handle objects of the allowed namespaces.": {
+			middleware: middleware.NamespaceFilter("ns1", "ns2"),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { return nil }
+			},
+			obj:       newPod("ns2", "test"),
+			expCalled: true,
+		},
+
+		"NamespaceFilter should ignore objects of not allowed namespaces.": {
+			middleware: middleware.NamespaceFilter("ns1", "ns2"),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(_ context.Context, _ runtime.Object) error { return errTest }
+			},
+			obj:       newPod("ns3", "test"),
+			expCalled: false,
+		},
+
+		"DryRun should mark the handler context as dry-run.": {
+			middleware: middleware.DryRun(),
+			handler: func(t *testing.T) controller.HandlerFunc {
+				return func(ctx context.Context, _ runtime.Object) error {
+					assert.True(t, middleware.IsDryRun(ctx))
+					return nil
+				}
+			},
+			obj:       newPod("test-ns", "test"),
+			expCalled: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			called := false
+			h := test.handler(t)
+			mh := test.middleware(controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+				called = true
+				return h(ctx, obj)
+			}))
+
+			err := mh.Handle(context.TODO(), test.obj)
+
+			assert.Equal(test.expCalled, called)
+			if test.expErr != nil {
+				assert.ErrorIs(err, test.expErr)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	errTest := errors.New("wanted error")
+
+	tests := map[string]struct {
+		obj        runtime.Object
+		handlerErr error
+		expType    string
+		expSpanErr error
+	}{
+		"Tracing should end the span with the handler error.": {
+			obj:        newPod("test-ns", "test"),
+			handlerErr: errTest,
+			expType:    "*v1.Pod",
+			expSpanErr: errTest,
+		},
+
+		"Tracing should not end the span with an error on requeue results.": {
+			obj:        newPod("test-ns", "test"),
+			handlerErr: controller.Requeue(),
+			expType:    "*v1.Pod",
+			expSpanErr: nil,
+		},
+
+		"Tracing should use the object GVK as the type if the object has it.": {
+			obj: &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "test"},
+			},
+			expType: "/v1, Kind=Pod",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			tracer := &testTracer{}
+
+			h := middleware.Tracing(tracer)(controller.HandlerFunc(func(_ context.Context, _ runtime.Object) error {
+				return test.handlerErr
+			}))
+
+			err := h.Handle(context.TODO(), test.obj)
+			assert.ErrorIs(err, test.handlerErr)
+			assert.Equal("kooper.handle", tracer.name)
+			assert.Equal("test-ns/test", tracer.attributes["object.key"])
+			assert.Equal(test.expType, tracer.attributes["object.type"])
+			assert.Equal(test.expSpanErr, tracer.err)
+		})
+	}
+}
+
+func TestIsDryRunWithoutMark(t *testing.T) {
+	assert.False(t, middleware.IsDryRun(context.TODO()))
+}
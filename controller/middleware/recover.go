@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+// Recover returns a middleware that recovers from the handler panics, the panics are logged
+// with their stack trace and converted to `controller.ErrHandlerPanic` errors.
+func Recover(logger log.Logger) Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.WithKV(log.KV{"object-key": objectKey(obj)}).
+						Errorf("handler panic recovered: %v\n%s", r, debug.Stack())
+					err = fmt.Errorf("%w: %v", controller.ErrHandlerPanic, r)
+				}
+			}()
+
+			return next.Handle(ctx, obj)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Timeout returns a middleware that sets a deadline on the context received by the handler.
+//
+// This middleware relies on the handler respecting the context, check `controller.Config.HandlerTimeout`
+// if you need to stop waiting for handlers that don't respect it.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return next.Handle(ctx, obj)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Tracer knows how to trace the handlings, it can be implemented with any tracing
+// library (e.g OpenTelemetry).
+type Tracer interface {
+	// StartSpan starts a span and returns the context with the span and a function to end the
+	// span with the result of the traced operation.
+	StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func(err error))
+}
+
+// Tracing returns a middleware that traces the handlings using the tracer, the requeue
+// results are not errors so the spans will end without error.
+func Tracing(tracer Tracer) Middleware {
+	return func(next controller.Handler) controller.Handler {
+		return controller.HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+			ctx, end := tracer.StartSpan(ctx, "kooper.handle", map[string]string{
+				"object.key":  objectKey(obj),
+				"object.type": objectType(obj),
+			})
+
+			err := next.Handle(ctx, obj)
+			if controller.IsRequeue(err) {
+				end(nil)
+			} else {
+				end(err)
+			}
+
+			return err
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"maps"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EventType is the type of a resource event.
+type EventType string
+
+const (
+	// EventAdd is the event of an added object (also received for every object on the first sync).
+	EventAdd EventType = "add"
+	// EventUpdate is the event of an updated object (also received on every resync).
+	EventUpdate EventType = "update"
+	// EventDelete is the event of a deleted object.
+	EventDelete EventType = "delete"
+)
+
+// Event is a resource event received by the controller before being queued.
+type Event struct {
+	// Type is the event type.
+	Type EventType
+	// Object is the object of the event, on delete events it's the last known state of the object.
+	Object runtime.Object
+	// OldObject is the previous state of the object, only set on update events.
+	OldObject runtime.Object
+}
+
+// Predicate knows if a resource event should be handled by the controller, the events
+// that are not allowed will not be queued.
+type Predicate interface {
+	Allow(ev Event) bool
+}
+
+// PredicateFunc is a helper to create Predicates from functions.
+type PredicateFunc func(ev Event) bool
+
+// Allow satisfies controller.Predicate interface.
+func (p PredicateFunc) Allow(ev Event) bool { return p(ev) }
+
+// allowEvent returns true if the event is allowed by all the predicates.
+func allowEvent(preds []Predicate, ev Event) bool {
+	for _, p := range preds {
+		if !p.Allow(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// PredicateAny returns a Predicate that allows the events allowed by any of the received predicates
+// (e.g generation or labels changed).
+func PredicateAny(preds ...Predicate) Predicate {
+	return PredicateFunc(func(ev Event) bool {
+		for _, p := range preds {
+			if p.Allow(ev) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// updateChanged returns a Predicate that only allows the update events where the received
+// changed func returns true, the rest of the event types are allowed.
+func updateChanged(changed func(old, new metav1.Object) bool) Predicate {
+	return PredicateFunc(func(ev Event) bool {
+		if ev.Type != EventUpdate || ev.OldObject == nil || ev.Object == nil {
+			return true
+		}
+
+		old, err := meta.Accessor(ev.OldObject)
+		if err != nil {
+			return true
+		}
+		new, err := meta.Accessor(ev.Object)
+		if err != nil {
+			return true
+		}
+
+		return changed(old, new)
+	})
+}
+
+// PredicateResourceVersionChanged returns a Predicate that ignores the update events where the object
+// resource version didn't change (e.g resyncs).
+func PredicateResourceVersionChanged() Predicate {
+	return updateChanged(func(old, new metav1.Object) bool {
+		return old.GetResourceVersion() != new.GetResourceVersion()
+	})
+}
+
+// PredicateGenerationChanged returns a Predicate that ignores the update events where the object generation
+// didn't change (e.g status only updates and resyncs). Take into account that not all the resources
+// increment the generation (e.g ConfigMaps).
+func PredicateGenerationChanged() Predicate {
+	return updateChanged(func(old, new metav1.Object) bool {
+		return old.GetGeneration() != new.GetGeneration()
+	})
+}
+
+// PredicateLabelsChanged returns a Predicate that ignores the update events where the object labels didn't change.
+func PredicateLabelsChanged() Predicate {
+	return updateChanged(func(old, new metav1.Object) bool {
+		return !maps.Equal(old.GetLabels(), new.GetLabels())
+	})
+}
+
+// PredicateAnnotationsChanged returns a Predicate that ignores the update events where the object annotations didn't change.
+func PredicateAnnotationsChanged() Predicate {
+	return updateChanged(func(old, new metav1.Object) bool {
+		return !maps.Equal(old.GetAnnotations(), new.GetAnnotations())
+	})
+}
+
+// PredicateNamespace returns a Predicate that only allows the events of objects on the received namespaces.
+func PredicateNamespace(namespaces ...string) Predicate {
+	allowed := map[string]struct{}{}
+	for _, ns := range namespaces {
+		allowed[ns] = struct{}{}
+	}
+
+	return PredicateFunc(func(ev Event) bool {
+		m, err := meta.Accessor(ev.Object)
+		if err != nil {
+			return false
+		}
+		_, ok := allowed[m.GetNamespace()]
+		return ok
+	})
+}
+
+// PredicateLabelSelector returns a Predicate that only allows the events of objects that match the label selector.
+//
+// On update events, if the old object matched the selector it will be allowed, so the handler knows
+// the object doesn't match anymore.
+func PredicateLabelSelector(selector labels.Selector) Predicate {
+	matches := func(obj runtime.Object) bool {
+		if obj == nil {
+			return false
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		return selector.Matches(labels.Set(m.GetLabels()))
+	}
+
+	return PredicateFunc(func(ev Event) bool {
+		return matches(ev.Object) || matches(ev.OldObject)
+	})
+}
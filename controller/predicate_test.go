@@ -0,0 +1,183 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+func newPredicatePod(ns string, rv string, gen int64, lbls, annots map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "test",
+		Namespace:       ns,
+		ResourceVersion: rv,
+		Generation:      gen,
+		Labels:          lbls,
+		Annotations:     annots,
+	}}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := map[string]struct {
+		predicate controller.Predicate
+		event     controller.Event
+		expAllow  bool
+	}{
+		"Resource version changed should allow add events.": {
+			predicate: controller.PredicateResourceVersionChanged(),
+			event:     controller.Event{Type: controller.EventAdd, Object: newPredicatePod("ns1", "1", 1, nil, nil)},
+			expAllow:  true,
+		},
+
+		"Resource version changed should ignore updates with the same resource version.": {
+			predicate: controller.PredicateResourceVersionChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "1", 1, nil, nil),
+			},
+			expAllow: false,
+		},
+
+		"Resource version changed should allow updates with different resource version.": {
+			predicate: controller.PredicateResourceVersionChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 1, nil, nil),
+			},
+			expAllow: true,
+		},
+
+		"Generation changed should ignore updates with the same generation.": {
+			predicate: controller.PredicateGenerationChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 1, nil, nil),
+			},
+			expAllow: false,
+		},
+
+		"Generation changed should allow updates with different generation.": {
+			predicate: controller.PredicateGenerationChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 2, nil, nil),
+			},
+			expAllow: true,
+		},
+
+		"Generation changed should allow delete events.": {
+			predicate: controller.PredicateGenerationChanged(),
+			event:     controller.Event{Type: controller.EventDelete, Object: newPredicatePod("ns1", "1", 1, nil, nil)},
+			expAllow:  true,
+		},
+
+		"Labels changed should ignore updates with the same labels.": {
+			predicate: controller.PredicateLabelsChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, map[string]string{"k": "v"}, nil),
+				Object:    newPredicatePod("ns1", "2", 2, map[string]string{"k": "v"}, nil),
+			},
+			expAllow: false,
+		},
+
+		"Labels changed should allow updates with different labels.": {
+			predicate: controller.PredicateLabelsChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, map[string]string{"k": "v"}, nil),
+				Object:    newPredicatePod("ns1", "2", 1, map[string]string{"k": "v2"}, nil),
+			},
+			expAllow: true,
+		},
+
+		"Annotations changed should ignore updates with the same annotations.": {
+			predicate: controller.PredicateAnnotationsChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, map[string]string{"k": "v"}),
+				Object:    newPredicatePod("ns1", "2", 2, nil, map[string]string{"k": "v"}),
+			},
+			expAllow: false,
+		},
+
+		"Annotations changed should allow updates with different annotations.": {
+			predicate: controller.PredicateAnnotationsChanged(),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 1, nil, map[string]string{"k": "v"}),
+			},
+			expAllow: true,
+		},
+
+		"Any should allow the events allowed by one of the predicates.": {
+			predicate: controller.PredicateAny(controller.PredicateGenerationChanged(), controller.PredicateLabelsChanged()),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 1, map[string]string{"k": "v"}, nil),
+			},
+			expAllow: true,
+		},
+
+		"Any should ignore the events not allowed by any of the predicates.": {
+			predicate: controller.PredicateAny(controller.PredicateGenerationChanged(), controller.PredicateLabelsChanged()),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, nil, nil),
+				Object:    newPredicatePod("ns1", "2", 1, nil, map[string]string{"k": "v"}),
+			},
+			expAllow: false,
+		},
+
+		"Namespace should allow events of objects on the namespaces.": {
+			predicate: controller.PredicateNamespace("ns1", "ns2"),
+			event:     controller.Event{Type: controller.EventAdd, Object: newPredicatePod("ns2", "1", 1, nil, nil)},
+			expAllow:  true,
+		},
+
+		"Namespace should ignore events of objects on other namespaces.": {
+			predicate: controller.PredicateNamespace("ns1", "ns2"),
+			event:     controller.Event{Type: controller.EventAdd, Object: newPredicatePod("ns3", "1", 1, nil, nil)},
+			expAllow:  false,
+		},
+
+		"Label selector should allow events of objects that match the selector.": {
+			predicate: controller.PredicateLabelSelector(labels.SelectorFromSet(labels.Set{"k": "v"})),
+			event:     controller.Event{Type: controller.EventAdd, Object: newPredicatePod("ns1", "1", 1, map[string]string{"k": "v"}, nil)},
+			expAllow:  true,
+		},
+
+		"Label selector should ignore events of objects that don't match the selector.": {
+			predicate: controller.PredicateLabelSelector(labels.SelectorFromSet(labels.Set{"k": "v"})),
+			event:     controller.Event{Type: controller.EventAdd, Object: newPredicatePod("ns1", "1", 1, map[string]string{"k": "v2"}, nil)},
+			expAllow:  false,
+		},
+
+		"Label selector should allow updates of objects that don't match the selector anymore.": {
+			predicate: controller.PredicateLabelSelector(labels.SelectorFromSet(labels.Set{"k": "v"})),
+			event: controller.Event{
+				Type:      controller.EventUpdate,
+				OldObject: newPredicatePod("ns1", "1", 1, map[string]string{"k": "v"}, nil),
+				Object:    newPredicatePod("ns1", "2", 1, nil, nil),
+			},
+			expAllow: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expAllow, test.predicate.Allow(test.event))
+		})
+	}
+}
@@ -2,7 +2,12 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -24,19 +29,43 @@ func (p processorFunc) Process(ctx context.Context, key string) error { return p
 // newIndexerProcessor returns a processor that processes a key that will get the kubernetes object
 // from a cache called indexer were the kubernetes watch updates have been indexed and stored
 // by the listerwatchers from the informers.
-func newIndexerProcessor(indexer cache.Indexer, handler Handler) processor {
+//
+// The handlers will receive a Lister of the indexer on the context.
+//
+// If the object doesn't exist and the deleted objects store has its last known state, it will
+// be handled by the delete handler (if any).
+func newIndexerProcessor(indexer cache.Indexer, deleted *deletedObjectStore, handler Handler, deleteHandler Handler) processor {
+	lister := newIndexerLister(indexer)
 	return processorFunc(func(ctx context.Context, key string) error {
+		// Let the handlers use the same cache.
+		ctx = contextWithLister(ctx, lister)
+
 		// Get the object
 		obj, exists, err := indexer.GetByKey(key)
 		if err != nil {
 			return err
 		}
 
-		if !exists {
+		if exists {
+			return handler.Handle(ctx, obj.(runtime.Object))
+		}
+
+		if deleteHandler == nil || deleted == nil {
 			return nil
 		}
 
-		return handler.Handle(ctx, obj.(runtime.Object))
+		lastObj, ok := deleted.Get(key)
+		if !ok {
+			return nil
+		}
+
+		err = deleteHandler.Handle(ctx, lastObj)
+		if err != nil {
+			return err
+		}
+		deleted.Delete(key)
+
+		return nil
 	})
 }
 
@@ -47,23 +76,174 @@ var errRequeued = fmt.Errorf("requeued after receiving error")
 // again to a queue if it has retrys pending.
 //
 // If the processing errored and has been retried, it will return a `errRequeued` error.
-func newRetryProcessor(name string, queue blockingQueue, logger log.Logger, next processor) processor {
+// Requeue results are ignored and permanent errors will not be retried.
+func newRetryProcessor(name string, queue Queue, logger log.Logger, next processor) processor {
 	return processorFunc(func(ctx context.Context, key string) error {
 		err := next.Process(ctx, key)
-		if err != nil {
-			// Retry if possible.
-			requeueErr := queue.Requeue(ctx, key)
-			if requeueErr != nil {
-				return fmt.Errorf("could not retry: %s: %w", requeueErr, err)
-			}
-			logger.WithKV(log.KV{"object-key": key}).Warningf("item requeued due to processing error: %s", err)
+		if err == nil {
 			return nil
 		}
 
+		if _, ok := requeueResultFromError(err); ok {
+			return err
+		}
+
+		if IsPermanent(err) {
+			return err
+		}
+
+		// Retry if possible.
+		requeueErr := queue.Requeue(ctx, key)
+		if requeueErr != nil {
+			return fmt.Errorf("could not retry: %s: %w", requeueErr, err)
+		}
+
+		return fmt.Errorf("%w: %w", errRequeued, err)
+	})
+}
+
+// newRequeueProcessor returns a processor that will requeue the keys when the handler asks for it
+// using the requeue results (e.g `RequeueAfter`). These requeues are not processing errors.
+//
+// When the key has been processed correctly or with an error that will not be retried (e.g permanent,
+// no retries left...) it will forget the key, so the requeue tracking is reset.
+func newRequeueProcessor(queue Queue, logger log.Logger, next processor) processor {
+	return processorFunc(func(ctx context.Context, key string) error {
+		err := next.Process(ctx, key)
+		if err == nil {
+			queue.Forget(ctx, key)
+			return nil
+		}
+
+		rr, ok := requeueResultFromError(err)
+		if !ok {
+			if !errors.Is(err, errRequeued) {
+				queue.Forget(ctx, key)
+			}
+			return err
+		}
+
+		if rr.after > 0 {
+			queue.AddAfter(ctx, key, rr.after)
+		} else {
+			queue.AddRateLimited(ctx, key)
+		}
+		logger.WithKV(log.KV{"object-key": key}).Debugf("item requeued: %s", rr)
+
 		return nil
 	})
 }
 
+// ErrHandlerPanic will be used when the handling of an object panics.
+var ErrHandlerPanic = errors.New("handler panic")
+
+// newRecoverProcessor returns a processor that will recover from the processing panics, the panics are
+// converted to `ErrHandlerPanic` errors (so they are retried like any other error) and logged with
+// their stack trace.
+func newRecoverProcessor(name string, logger log.Logger, mrec MetricsRecorder, next processor) processor {
+	return processorFunc(func(ctx context.Context, key string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				mrec.IncResourceProcessingPanic(ctx, name)
+				logger.WithKV(log.KV{"object-key": key}).Errorf("panic recovered while processing: %v\n%s", r, debug.Stack())
+				err = fmt.Errorf("%w: %v", ErrHandlerPanic, r)
+			}
+		}()
+
+		return next.Process(ctx, key)
+	})
+}
+
+// ErrHandlerTimeout will be used when the handling of an object has reached the handling timeout.
+var ErrHandlerTimeout = errors.New("handler timeout")
+
+// timeoutProcessor is a processor that will cancel the processing context once the timeout
+// has been reached, the processing is executed in a different goroutine so a hung handler that
+// doesn't respect the context doesn't block the worker.
+//
+// If the processing timeouts it will return a `ErrHandlerTimeout` error. The abandoned processings
+// are tracked until they end, so the key can be held (not processed concurrently) and the controller
+// can wait for them when stopping (up to the shutdown timeout).
+type timeoutProcessor struct {
+	name    string
+	timeout time.Duration
+	mrec    MetricsRecorder
+	next    processor
+
+	mu         sync.Mutex
+	processing map[string]chan struct{}
+	wg         sync.WaitGroup
+}
+
+func newTimeoutProcessor(name string, timeout time.Duration, mrec MetricsRecorder, next processor) *timeoutProcessor {
+	return &timeoutProcessor{
+		name:       name,
+		timeout:    timeout,
+		mrec:       mrec,
+		next:       next,
+		processing: map[string]chan struct{}{},
+	}
+}
+
+func (t *timeoutProcessor) Process(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	doneC := make(chan struct{})
+	t.mu.Lock()
+	t.processing[key] = doneC
+	t.mu.Unlock()
+
+	errC := make(chan error, 1)
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer func() {
+			t.mu.Lock()
+			delete(t.processing, key)
+			t.mu.Unlock()
+			close(doneC)
+		}()
+
+		errC <- t.next.Process(ctx, key)
+	}()
+
+	select {
+	case err := <-errC:
+		return err
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ctx.Err()
+		}
+		t.mrec.IncResourceProcessingTimeout(ctx, t.name)
+		return fmt.Errorf("%w: processing took more than %s", ErrHandlerTimeout, t.timeout)
+	}
+}
+
+// running returns a channel that will be closed once the abandoned processing of the key ends,
+// nil if the key is not being processed.
+func (t *timeoutProcessor) running(key string) <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if doneC, ok := t.processing[key]; ok {
+		return doneC
+	}
+	return nil
+}
+
+// processingKeys returns the keys that are being processed (abandoned or not).
+func (t *timeoutProcessor) processingKeys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Collect(maps.Keys(t.processing))
+}
+
+// wait waits until all the abandoned processings end, it should be called once no more keys are
+// being processed.
+func (t *timeoutProcessor) wait() {
+	t.wg.Wait()
+}
+
 // newMetricsProcessor returns a processor that measures everything related with the processing logic.
 func newMetricsProcessor(name string, mrec MetricsRecorder, next processor) processor {
 	return processorFunc(func(ctx context.Context, key string) (err error) {
@@ -11,15 +11,24 @@ import (
 	"github.com/spotahome/kooper/v2/log"
 )
 
-// blockingQueue is a queue that any of its implementations should
-// implement a blocking get mechanism.
-type blockingQueue interface {
+// Queue is the queue where the controller stores the object keys that need to be processed,
+// any of its implementations should implement a blocking get mechanism.
+//
+// The controller will shut down the queue when it stops running.
+type Queue interface {
 	// Add will add an item to the queue.
 	Add(ctx context.Context, item interface{})
 	// Requeue will add an item to the queue in a requeue mode.
 	// If doesn't accept requeueing or max requeue have been reached
 	// it will return an error.
 	Requeue(ctx context.Context, item interface{}) error
+	// AddAfter will add an item to the queue after the received duration.
+	AddAfter(ctx context.Context, item interface{}, d time.Duration)
+	// AddRateLimited will add an item to the queue when the rate limiter says it's ok,
+	// it doesn't have a max requeue limit and it doesn't count as a requeue of `Requeue`.
+	AddRateLimited(ctx context.Context, item interface{})
+	// Forget will reset the requeue tracking of an item.
+	Forget(ctx context.Context, item interface{})
 	// Get is a blocking operation, if the last object usage has not been finished (`done`)
 	// being used it will block until this has been done.
 	Get(ctx context.Context) (item interface{}, shutdown bool)
@@ -38,43 +47,76 @@ var (
 type rateLimitingBlockingQueue struct {
 	maxRetries int
 	queue      workqueue.TypedRateLimitingInterface[any]
+
+	// retries are tracked apart from the rate limiter requeues, so the rate limited
+	// requeues (e.g requeue results) don't use the retries.
+	mu      sync.Mutex
+	retries map[interface{}]int
+}
+
+// NewRateLimitingQueue returns a new Queue that will use the rate limiter for the requeues, the requeues of
+// an item will be limited to max retries.
+func NewRateLimitingQueue(maxRetries int, rateLimiter workqueue.TypedRateLimiter[any]) Queue {
+	return newRateLimitingBlockingQueue(maxRetries, workqueue.NewTypedRateLimitingQueue(rateLimiter))
 }
 
-func newRateLimitingBlockingQueue(maxRetries int, queue workqueue.TypedRateLimitingInterface[any]) blockingQueue {
-	return rateLimitingBlockingQueue{
+func newRateLimitingBlockingQueue(maxRetries int, queue workqueue.TypedRateLimitingInterface[any]) Queue {
+	return &rateLimitingBlockingQueue{
 		maxRetries: maxRetries,
 		queue:      queue,
+		retries:    map[interface{}]int{},
 	}
 }
 
-func (r rateLimitingBlockingQueue) Add(_ context.Context, item interface{}) {
+func (r *rateLimitingBlockingQueue) Add(_ context.Context, item interface{}) {
 	r.queue.Add(item)
 }
 
-func (r rateLimitingBlockingQueue) Requeue(_ context.Context, item interface{}) error {
+func (r *rateLimitingBlockingQueue) Requeue(_ context.Context, item interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	// If there was an error and we have retries pending then requeue.
-	if r.queue.NumRequeues(item) < r.maxRetries {
+	if r.retries[item] < r.maxRetries {
+		r.retries[item]++
 		r.queue.AddRateLimited(item)
 		return nil
 	}
 
+	delete(r.retries, item)
 	r.queue.Forget(item)
 	return errMaxRetriesReached
 }
 
-func (r rateLimitingBlockingQueue) Get(_ context.Context) (item interface{}, shutdown bool) {
+func (r *rateLimitingBlockingQueue) AddAfter(_ context.Context, item interface{}, d time.Duration) {
+	r.queue.AddAfter(item, d)
+}
+
+func (r *rateLimitingBlockingQueue) AddRateLimited(_ context.Context, item interface{}) {
+	r.queue.AddRateLimited(item)
+}
+
+func (r *rateLimitingBlockingQueue) Forget(_ context.Context, item interface{}) {
+	r.mu.Lock()
+	delete(r.retries, item)
+	r.mu.Unlock()
+
+	r.queue.Forget(item)
+}
+
+func (r *rateLimitingBlockingQueue) Get(_ context.Context) (item interface{}, shutdown bool) {
 	return r.queue.Get()
 }
 
-func (r rateLimitingBlockingQueue) Done(_ context.Context, item interface{}) {
+func (r *rateLimitingBlockingQueue) Done(_ context.Context, item interface{}) {
 	r.queue.Done(item)
 }
 
-func (r rateLimitingBlockingQueue) ShutDown(_ context.Context) {
+func (r *rateLimitingBlockingQueue) ShutDown(_ context.Context) {
 	r.queue.ShutDown()
 }
 
-func (r rateLimitingBlockingQueue) Len(_ context.Context) int {
+func (r *rateLimitingBlockingQueue) Len(_ context.Context) int {
 	return r.queue.Len()
 }
 
@@ -85,23 +127,17 @@ type metricsBlockingQueue struct {
 	mrec          MetricsRecorder
 	itemsQueuedAt map[interface{}]time.Time
 	logger        log.Logger
-	queue         blockingQueue
+	queue         Queue
 }
 
-func newMetricsBlockingQueue(name string, mrec MetricsRecorder, queue blockingQueue, logger log.Logger) (blockingQueue, error) {
-	// Register func/callback based metrics. These are controlled by the MetricsRecorder.
-	err := mrec.RegisterResourceQueueLengthFunc(name, func(ctx context.Context) int { return queue.Len(ctx) })
-	if err != nil {
-		return nil, err
-	}
-
+func newMetricsBlockingQueue(name string, mrec MetricsRecorder, queue Queue, logger log.Logger) Queue {
 	return &metricsBlockingQueue{
 		name:          name,
 		mrec:          mrec,
 		itemsQueuedAt: map[interface{}]time.Time{},
 		logger:        logger,
 		queue:         queue,
-	}, nil
+	}
 }
 
 func (m *metricsBlockingQueue) Add(ctx context.Context, item interface{}) {
@@ -126,6 +162,34 @@ func (m *metricsBlockingQueue) Requeue(ctx context.Context, item interface{}) er
 	return m.queue.Requeue(ctx, item)
 }
 
+func (m *metricsBlockingQueue) AddAfter(ctx context.Context, item interface{}, d time.Duration) {
+	// The item will not be ready in the queue until the duration has passed, so
+	// start measuring from there.
+	m.mu.Lock()
+	if _, ok := m.itemsQueuedAt[item]; !ok {
+		m.itemsQueuedAt[item] = time.Now().Add(d)
+	}
+	m.mu.Unlock()
+
+	m.mrec.IncResourceEventQueued(ctx, m.name, true)
+	m.queue.AddAfter(ctx, item, d)
+}
+
+func (m *metricsBlockingQueue) AddRateLimited(ctx context.Context, item interface{}) {
+	m.mu.Lock()
+	if _, ok := m.itemsQueuedAt[item]; !ok {
+		m.itemsQueuedAt[item] = time.Now()
+	}
+	m.mu.Unlock()
+
+	m.mrec.IncResourceEventQueued(ctx, m.name, true)
+	m.queue.AddRateLimited(ctx, item)
+}
+
+func (m *metricsBlockingQueue) Forget(ctx context.Context, item interface{}) {
+	m.queue.Forget(ctx, item)
+}
+
 func (m *metricsBlockingQueue) Get(ctx context.Context) (interface{}, bool) {
 	// Here should get blocked, warning with the mutexes.
 	item, shutdown := m.queue.Get(ctx)
@@ -136,6 +200,10 @@ func (m *metricsBlockingQueue) Get(ctx context.Context) (interface{}, bool) {
 	m.mu.Lock()
 	queuedAt, ok := m.itemsQueuedAt[item]
 	if ok {
+		// Delayed items could be dequeued before the delay if they were added again.
+		if now := time.Now(); queuedAt.After(now) {
+			queuedAt = now
+		}
 		m.mrec.ObserveResourceInQueueDuration(ctx, m.name, queuedAt)
 		delete(m.itemsQueuedAt, item)
 	} else {
@@ -157,6 +225,6 @@ func (m *metricsBlockingQueue) ShutDown(ctx context.Context) {
 
 func (m *metricsBlockingQueue) Len(ctx context.Context) int {
 	// Measurement controlled by the metrics recorder, so is implemented in callback
-	// mode, should be already registered, check factory. This is NOOP.
+	// mode, should be already registered, check controller factory. This is NOOP.
 	return m.queue.Len(ctx)
 }
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// NewDefaultRateLimiter returns the rate limiter used by the controllers by default. It's the
+// Kubernetes controllers default rate limiter (per item exponential backoff of 5ms-1000s and
+// an overall bucket limiter of 10 QPS with 100 burst).
+func NewDefaultRateLimiter() workqueue.TypedRateLimiter[any] {
+	return workqueue.DefaultTypedControllerRateLimiter[any]()
+}
+
+// NewFastRetryRateLimiter returns a rate limiter for fast retries, useful when the errors are
+// usually transient and the handling is cheap (per item exponential backoff of 5ms-5s and an
+// overall bucket limiter of 50 QPS with 300 burst).
+func NewFastRetryRateLimiter() workqueue.TypedRateLimiter[any] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[any](5*time.Millisecond, 5*time.Second),
+		&workqueue.TypedBucketRateLimiter[any]{Limiter: rate.NewLimiter(rate.Limit(50), 300)},
+	)
+}
+
+// NewSlowExternalAPIRateLimiter returns a rate limiter for handlers that call slow or rate limited
+// external APIs (per item exponential backoff with jitter of 1s-5m and an overall bucket limiter
+// of 5 QPS with 20 burst).
+func NewSlowExternalAPIRateLimiter() workqueue.TypedRateLimiter[any] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		NewExponentialJitterRateLimiter(1*time.Second, 5*time.Minute, 0.2),
+		&workqueue.TypedBucketRateLimiter[any]{Limiter: rate.NewLimiter(rate.Limit(5), 20)},
+	)
+}
+
+// NewFixedIntervalRateLimiter returns a rate limiter that will always wait the same interval.
+func NewFixedIntervalRateLimiter(interval time.Duration) workqueue.TypedRateLimiter[any] {
+	return workqueue.NewTypedItemFastSlowRateLimiter[any](interval, interval, 0)
+}
+
+// NewExponentialJitterRateLimiter returns a per item exponential backoff rate limiter (base*2^failures)
+// that adds a random jitter (up to jitter factor of the backoff, e.g 0.1 is up to a 10%) to
+// each backoff, so the retries of multiple items are spread. The backoff will not be greater than max.
+func NewExponentialJitterRateLimiter(base, max time.Duration, jitter float64) workqueue.TypedRateLimiter[any] {
+	return &exponentialJitterRateLimiter{
+		failures: map[any]int{},
+		base:     base,
+		max:      max,
+		jitter:   jitter,
+	}
+}
+
+type exponentialJitterRateLimiter struct {
+	mu       sync.Mutex
+	failures map[any]int
+	base     time.Duration
+	max      time.Duration
+	jitter   float64
+}
+
+func (e *exponentialJitterRateLimiter) When(item any) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exp := e.failures[item]
+	e.failures[item]++
+
+	backoff := float64(e.base.Nanoseconds()) * math.Pow(2, float64(exp))
+	if e.jitter > 0 {
+		backoff += backoff * e.jitter * rand.Float64()
+	}
+
+	if backoff > float64(e.max.Nanoseconds()) {
+		return e.max
+	}
+
+	return time.Duration(backoff)
+}
+
+func (e *exponentialJitterRateLimiter) NumRequeues(item any) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failures[item]
+}
+
+func (e *exponentialJitterRateLimiter) Forget(item any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.failures, item)
+}
@@ -0,0 +1,79 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+func TestExponentialJitterRateLimiter(t *testing.T) {
+	tests := map[string]struct {
+		base     time.Duration
+		max      time.Duration
+		jitter   float64
+		failures int
+		expMin   time.Duration
+		expMax   time.Duration
+	}{
+		"Without jitter the backoff should be exponential.": {
+			base:     10 * time.Millisecond,
+			max:      time.Minute,
+			failures: 4,
+			expMin:   80 * time.Millisecond,
+			expMax:   80 * time.Millisecond,
+		},
+
+		"With jitter the backoff should be exponential plus the jitter.": {
+			base:     10 * time.Millisecond,
+			max:      time.Minute,
+			jitter:   0.5,
+			failures: 4,
+			expMin:   80 * time.Millisecond,
+			expMax:   120 * time.Millisecond,
+		},
+
+		"The backoff should not be greater than the max.": {
+			base:     10 * time.Millisecond,
+			max:      50 * time.Millisecond,
+			jitter:   0.5,
+			failures: 10,
+			expMin:   50 * time.Millisecond,
+			expMax:   50 * time.Millisecond,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			rl := controller.NewExponentialJitterRateLimiter(test.base, test.max, test.jitter)
+			var got time.Duration
+			for i := 0; i < test.failures; i++ {
+				got = rl.When("test")
+			}
+
+			assert.Equal(test.failures, rl.NumRequeues("test"))
+			assert.GreaterOrEqual(got, test.expMin)
+			assert.LessOrEqual(got, test.expMax)
+
+			// Forgetting should reset the backoff.
+			rl.Forget("test")
+			assert.Equal(0, rl.NumRequeues("test"))
+			got = rl.When("test")
+			assert.GreaterOrEqual(got, test.base)
+			assert.LessOrEqual(got, time.Duration(float64(test.base)*(1+test.jitter)))
+		})
+	}
+}
+
+func TestFixedIntervalRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	rl := controller.NewFixedIntervalRateLimiter(42 * time.Second)
+	for i := 0; i < 5; i++ {
+		assert.Equal(42*time.Second, rl.When("test"))
+	}
+}
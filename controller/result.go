@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// requeueResult is the error returned by the handlers to ask the controller to requeue
+// the object key again. It's not treated as a processing error.
+type requeueResult struct {
+	after time.Duration
+}
+
+func (r requeueResult) Error() string {
+	if r.after <= 0 {
+		return "requeue requested"
+	}
+	return fmt.Sprintf("requeue after %s requested", r.after)
+}
+
+// RequeueAfter returns an error that a Handler can return to tell the controller that the
+// object key needs to be handled again after the received duration.
+//
+// This is not a processing error, it will not be measured as an error nor use the retries.
+func RequeueAfter(d time.Duration) error {
+	return requeueResult{after: d}
+}
+
+// Requeue returns an error that a Handler can return to tell the controller that the
+// object key needs to be handled again, the key will be requeued using the queue rate limiter.
+//
+// This is not a processing error, it will not be measured as an error nor use the retries.
+func Requeue() error {
+	return requeueResult{}
+}
+
+// permanentError is an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// Permanent wraps the received error so the controller knows that it's a permanent
+// error and the object key should be forgotten instead of retried.
+//
+// The processing will be measured as an error. If the error is nil, it will return nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent returns true if the error has been marked as permanent.
+func IsPermanent(err error) bool {
+	var perr permanentError
+	return errors.As(err, &perr)
+}
+
+// IsRequeue returns true if the error is a requeue result (e.g `Requeue`, `RequeueAfter`), these
+// are not processing errors.
+func IsRequeue(err error) bool {
+	_, ok := requeueResultFromError(err)
+	return ok
+}
+
+func requeueResultFromError(err error) (requeueResult, bool) {
+	var rr requeueResult
+	ok := errors.As(err, &rr)
+	return rr, ok
+}
@@ -0,0 +1,81 @@
+package retrieve
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Pods returns a retriever of Pods.
+func Pods(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.Pod] {
+	return FromClient[*corev1.Pod](cli.CoreV1().Pods(opts.Namespace), opts)
+}
+
+// Services returns a retriever of Services.
+func Services(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.Service] {
+	return FromClient[*corev1.Service](cli.CoreV1().Services(opts.Namespace), opts)
+}
+
+// ConfigMaps returns a retriever of ConfigMaps.
+func ConfigMaps(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.ConfigMap] {
+	return FromClient[*corev1.ConfigMap](cli.CoreV1().ConfigMaps(opts.Namespace), opts)
+}
+
+// Secrets returns a retriever of Secrets.
+func Secrets(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.Secret] {
+	return FromClient[*corev1.Secret](cli.CoreV1().Secrets(opts.Namespace), opts)
+}
+
+// ServiceAccounts returns a retriever of ServiceAccounts.
+func ServiceAccounts(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.ServiceAccount] {
+	return FromClient[*corev1.ServiceAccount](cli.CoreV1().ServiceAccounts(opts.Namespace), opts)
+}
+
+// Namespaces returns a retriever of Namespaces.
+func Namespaces(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.Namespace] {
+	return FromClient[*corev1.Namespace](cli.CoreV1().Namespaces(), opts)
+}
+
+// Nodes returns a retriever of Nodes.
+func Nodes(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*corev1.Node] {
+	return FromClient[*corev1.Node](cli.CoreV1().Nodes(), opts)
+}
+
+// Deployments returns a retriever of Deployments.
+func Deployments(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*appsv1.Deployment] {
+	return FromClient[*appsv1.Deployment](cli.AppsV1().Deployments(opts.Namespace), opts)
+}
+
+// StatefulSets returns a retriever of StatefulSets.
+func StatefulSets(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*appsv1.StatefulSet] {
+	return FromClient[*appsv1.StatefulSet](cli.AppsV1().StatefulSets(opts.Namespace), opts)
+}
+
+// DaemonSets returns a retriever of DaemonSets.
+func DaemonSets(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*appsv1.DaemonSet] {
+	return FromClient[*appsv1.DaemonSet](cli.AppsV1().DaemonSets(opts.Namespace), opts)
+}
+
+// ReplicaSets returns a retriever of ReplicaSets.
+func ReplicaSets(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*appsv1.ReplicaSet] {
+	return FromClient[*appsv1.ReplicaSet](cli.AppsV1().ReplicaSets(opts.Namespace), opts)
+}
+
+// Jobs returns a retriever of Jobs.
+func Jobs(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*batchv1.Job] {
+	return FromClient[*batchv1.Job](cli.BatchV1().Jobs(opts.Namespace), opts)
+}
+
+// CronJobs returns a retriever of CronJobs.
+func CronJobs(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*batchv1.CronJob] {
+	return FromClient[*batchv1.CronJob](cli.BatchV1().CronJobs(opts.Namespace), opts)
+}
+
+// Ingresses returns a retriever of Ingresses.
+func Ingresses(cli kubernetes.Interface, opts Options) controller.TypedRetriever[*networkingv1.Ingress] {
+	return FromClient[*networkingv1.Ingress](cli.NetworkingV1().Ingresses(opts.Namespace), opts)
+}
@@ -0,0 +1,16 @@
+package retrieve
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Dynamic returns a retriever of unstructured objects of any resource using the dynamic client, useful
+// for resources without generated clients (e.g third party CRDs). Use `controller.FromUnstructured`
+// or `controller.NewUnstructuredHandler` to convert them to typed objects.
+func Dynamic(cli dynamic.Interface, gvr schema.GroupVersionResource, opts Options) controller.TypedRetriever[*unstructured.Unstructured] {
+	return FromClient[*unstructured.Unstructured](cli.Resource(gvr).Namespace(opts.Namespace), opts)
+}
@@ -0,0 +1,70 @@
+package retrieve_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/spotahome/kooper/v2/controller/retrieve"
+)
+
+func newUnstructuredCR(ns, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Test",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": ns,
+		},
+	}}
+}
+
+func TestDynamicRetriever(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "tests"}
+
+	tests := map[string]struct {
+		opts    retrieve.Options
+		expObjs []string
+	}{
+		"Without namespace it should list all the objects.": {
+			opts:    retrieve.Options{},
+			expObjs: []string{"test1", "test2", "test3"},
+		},
+
+		"With namespace it should list the objects of the namespace.": {
+			opts:    retrieve.Options{Namespace: "ns2"},
+			expObjs: []string{"test3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cli := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{gvr: "TestList"},
+				newUnstructuredCR("ns1", "test1"),
+				newUnstructuredCR("ns1", "test2"),
+				newUnstructuredCR("ns2", "test3"),
+			)
+
+			ret := retrieve.Dynamic(cli, gvr, test.opts)
+			obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+			require.NoError(err)
+
+			got := []string{}
+			for _, u := range obj.(*unstructured.UnstructuredList).Items {
+				got = append(got, u.GetName())
+			}
+			assert.ElementsMatch(test.expObjs, got)
+		})
+	}
+}
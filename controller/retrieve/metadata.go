@@ -0,0 +1,51 @@
+package retrieve
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Metadata returns a retriever of metadata only objects (labels, annotations, owner references...) of any
+// resource using the metadata client. The controller local cache will only store the metadata, reducing
+// the memory on large collections (e.g Secrets), use it when the handlers only need the metadata or
+// they need the full objects only for a few of them (check `FullObject`).
+func Metadata(cli metadata.Interface, gvr schema.GroupVersionResource, opts Options) controller.TypedRetriever[*metav1.PartialObjectMetadata] {
+	return FromClient[*metav1.PartialObjectMetadata](cli.Resource(gvr).Namespace(opts.Namespace), opts)
+}
+
+// GetFunc gets an object by its namespace and name from the API server.
+type GetFunc[T runtime.Object] func(ctx context.Context, namespace, name string) (T, error)
+
+// FullObject gets from the API server the full object of a metadata only object (check `Metadata`).
+// If the object has been recreated since the metadata was retrieved (the UID doesn't match) it will fail.
+//
+//	secret, err := retrieve.FullObject(ctx, obj, func(ctx context.Context, ns, name string) (*corev1.Secret, error) {
+//		return k8scli.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+//	})
+func FullObject[T runtime.Object](ctx context.Context, obj *metav1.PartialObjectMetadata, get GetFunc[T]) (T, error) {
+	var empty T
+
+	full, err := get(ctx, obj.Namespace, obj.Name)
+	if err != nil {
+		return empty, fmt.Errorf("could not get full object: %w", err)
+	}
+
+	m, err := meta.Accessor(full)
+	if err != nil {
+		return empty, fmt.Errorf("object has no meta: %w", err)
+	}
+
+	if obj.UID != "" && m.GetUID() != obj.UID {
+		return empty, fmt.Errorf("full object UID %q doesn't match the metadata UID %q", m.GetUID(), obj.UID)
+	}
+
+	return full, nil
+}
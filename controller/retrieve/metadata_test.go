@@ -0,0 +1,115 @@
+package retrieve_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+
+	"github.com/spotahome/kooper/v2/controller/retrieve"
+)
+
+func newPartialSecret(ns, name string, uid types.UID) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, UID: uid},
+	}
+}
+
+func TestMetadataRetriever(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	tests := map[string]struct {
+		opts    retrieve.Options
+		expObjs []string
+	}{
+		"Without namespace it should list all the objects metadata.": {
+			opts:    retrieve.Options{},
+			expObjs: []string{"secret1", "secret2", "secret3"},
+		},
+
+		"With namespace it should list the objects metadata of the namespace.": {
+			opts:    retrieve.Options{Namespace: "ns1"},
+			expObjs: []string{"secret1", "secret2"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			scheme := metadatafake.NewTestScheme()
+			require.NoError(metav1.AddMetaToScheme(scheme))
+			cli := metadatafake.NewSimpleMetadataClient(scheme,
+				newPartialSecret("ns1", "secret1", "uid1"),
+				newPartialSecret("ns1", "secret2", "uid2"),
+				newPartialSecret("ns2", "secret3", "uid3"),
+			)
+
+			ret := retrieve.Metadata(cli, gvr, test.opts)
+			obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+			require.NoError(err)
+
+			got := []string{}
+			for _, m := range obj.(*metav1.PartialObjectMetadataList).Items {
+				got = append(got, m.Name)
+			}
+			assert.ElementsMatch(test.expObjs, got)
+		})
+	}
+}
+
+func TestFullObject(t *testing.T) {
+	tests := map[string]struct {
+		obj       *metav1.PartialObjectMetadata
+		expSecret *corev1.Secret
+		expErr    bool
+	}{
+		"Getting the full object should return the object.": {
+			obj: newPartialSecret("ns1", "secret1", "uid1"),
+			expSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "secret1", UID: "uid1"},
+				Data:       map[string][]byte{"k": []byte("v")},
+			},
+		},
+
+		"Getting the full object of a recreated object should fail.": {
+			obj:    newPartialSecret("ns1", "secret1", "uid0"),
+			expErr: true,
+		},
+
+		"Getting the full object of a missing object should fail.": {
+			obj:    newPartialSecret("ns1", "secret2", "uid2"),
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			cli := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "secret1", UID: "uid1"},
+				Data:       map[string][]byte{"k": []byte("v")},
+			})
+
+			secret, err := retrieve.FullObject(context.TODO(), test.obj, func(ctx context.Context, ns, name string) (*corev1.Secret, error) {
+				return cli.CoreV1().Secrets(ns).Get(ctx, name, metav1.GetOptions{})
+			})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSecret, secret)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package retrieve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/pager"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// MultiNamespace returns a retriever that retrieves the objects of multiple namespaces as a single retriever,
+// useful when the controller doesn't have cluster wide permissions. The objects of each namespace are
+// retrieved with their own retriever (e.g `retrieve.Pods(k8scli, retrieve.Options{Namespace: ns})`).
+//
+// The lists of all the namespaces are merged in a single list and the watches are multiplexed in a single
+// watch, the resource versions are tracked per namespace, so the watches of each namespace are resumed
+// from their own resource version.
+func MultiNamespace[T runtime.Object](namespaces []string, newRetriever func(namespace string) controller.TypedRetriever[T]) controller.TypedRetriever[T] {
+	rets := make(map[string]controller.Retriever, len(namespaces))
+	for _, ns := range namespaces {
+		rets[ns] = newRetriever(ns)
+	}
+
+	return controller.NewTypedRetriever[T](&multiNamespaceRetriever{
+		namespaces:       namespaces,
+		retrievers:       rets,
+		resourceVersions: map[string]string{},
+	})
+}
+
+type multiNamespaceRetriever struct {
+	namespaces []string
+	retrievers map[string]controller.Retriever
+
+	mu sync.Mutex
+	// resourceVersions are the last known resource versions of each namespace.
+	resourceVersions map[string]string
+}
+
+// encodeResourceVersion encodes the resource versions of all the namespaces as a single resource version.
+func encodeResourceVersion(rvs map[string]string) (string, error) {
+	data, err := json.Marshal(rvs)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// namespaceResourceVersions returns the resource version that should be used on each namespace for a
+// requested resource version. The requested one could be one of our composite resource versions, a
+// resource version of a single namespace object (the informer uses the last received object resource
+// version) or a special one (e.g `0`) that can be used on all namespaces.
+func (m *multiNamespaceRetriever) namespaceResourceVersions(rv string) map[string]string {
+	rvs := map[string]string{}
+	if err := json.Unmarshal([]byte(rv), &rvs); err == nil {
+		return rvs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ns := range m.namespaces {
+		nsRV, ok := m.resourceVersions[ns]
+		if rv == "" || rv == "0" || !ok {
+			nsRV = rv
+		}
+		rvs[ns] = nsRV
+	}
+
+	return rvs
+}
+
+func (m *multiNamespaceRetriever) setResourceVersion(ns, rv string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resourceVersions[ns] = rv
+}
+
+func (m *multiNamespaceRetriever) List(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+	if len(m.namespaces) == 0 {
+		return nil, fmt.Errorf("at least one namespace is required")
+	}
+
+	rvs := m.namespaceResourceVersions(options.ResourceVersion)
+
+	var result runtime.Object
+	items := []runtime.Object{}
+	listRVs := map[string]string{}
+	for _, ns := range m.namespaces {
+		// The pages of each namespace can't be merged, so we get all the pages of each namespace.
+		ret := m.retrievers[ns]
+		p := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return ret.List(ctx, opts)
+		})
+
+		nsOptions := options
+		nsOptions.ResourceVersion = rvs[ns]
+		nsOptions.Continue = ""
+		list, _, err := p.List(ctx, nsOptions)
+		if err != nil {
+			return nil, fmt.Errorf("could not list namespace %q: %w", ns, err)
+		}
+
+		nsItems, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, fmt.Errorf("could not extract namespace %q list items: %w", ns, err)
+		}
+		items = append(items, nsItems...)
+
+		lm, err := meta.ListAccessor(list)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %q list has no meta: %w", ns, err)
+		}
+		listRVs[ns] = lm.GetResourceVersion()
+
+		if result == nil {
+			result = list
+		}
+	}
+
+	err := meta.SetList(result, items)
+	if err != nil {
+		return nil, fmt.Errorf("could not set list items: %w", err)
+	}
+
+	rv, err := encodeResourceVersion(listRVs)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode resource version: %w", err)
+	}
+
+	lm, err := meta.ListAccessor(result)
+	if err != nil {
+		return nil, fmt.Errorf("list has no meta: %w", err)
+	}
+	lm.SetResourceVersion(rv)
+	lm.SetContinue("")
+
+	for ns, nsRV := range listRVs {
+		m.setResourceVersion(ns, nsRV)
+	}
+
+	return result, nil
+}
+
+func (m *multiNamespaceRetriever) Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	if len(m.namespaces) == 0 {
+		return nil, fmt.Errorf("at least one namespace is required")
+	}
+
+	rvs := m.namespaceResourceVersions(options.ResourceVersion)
+
+	mw := &multiWatch{
+		resultC: make(chan watch.Event),
+		stopC:   make(chan struct{}),
+	}
+	for _, ns := range m.namespaces {
+		nsOptions := options
+		nsOptions.ResourceVersion = rvs[ns]
+		w, err := m.retrievers[ns].Watch(ctx, nsOptions)
+		if err != nil {
+			mw.Stop()
+			return nil, fmt.Errorf("could not watch namespace %q: %w", ns, err)
+		}
+		mw.watchers = append(mw.watchers, w)
+	}
+
+	var wg sync.WaitGroup
+	for i, ns := range m.namespaces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mw.forward(mw.watchers[i], func(ev watch.Event) {
+				if ev.Type == watch.Error {
+					return
+				}
+				if obj, err := meta.Accessor(ev.Object); err == nil {
+					m.setResourceVersion(ns, obj.GetResourceVersion())
+				}
+			})
+		}()
+	}
+
+	// Once all the watches have ended, end ours.
+	go func() {
+		wg.Wait()
+		close(mw.resultC)
+	}()
+
+	return mw, nil
+}
+
+// multiWatch multiplexes multiple watches in a single one, if any of the watches ends, all of them will end.
+type multiWatch struct {
+	watchers []watch.Interface
+	resultC  chan watch.Event
+	stopC    chan struct{}
+	stopOnce sync.Once
+}
+
+// forward forwards the events of the watch to the multiplexed watch, onEvent will be called with the delivered events.
+func (m *multiWatch) forward(w watch.Interface, onEvent func(watch.Event)) {
+	defer m.Stop()
+
+	for {
+		select {
+		case <-m.stopC:
+			return
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			select {
+			case m.resultC <- ev:
+				// Only track the events that have been delivered.
+				onEvent(ev)
+			case <-m.stopC:
+				return
+			}
+		}
+	}
+}
+
+func (m *multiWatch) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stopC)
+		for _, w := range m.watchers {
+			w.Stop()
+		}
+	})
+}
+
+func (m *multiWatch) ResultChan() <-chan watch.Event { return m.resultC }
@@ -0,0 +1,141 @@
+package retrieve_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/retrieve"
+)
+
+func TestMultiNamespaceRetrieverList(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := fake.NewSimpleClientset(
+		newPod("ns1", "pod1", nil),
+		newPod("ns1", "pod2", nil),
+		newPod("ns2", "pod3", nil),
+		newPod("ns3", "pod4", nil),
+	)
+
+	ret := retrieve.MultiNamespace([]string{"ns1", "ns2"}, func(ns string) controller.TypedRetriever[*corev1.Pod] {
+		return retrieve.Pods(cli, retrieve.Options{Namespace: ns})
+	})
+
+	obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+
+	got := []string{}
+	for _, p := range obj.(*corev1.PodList).Items {
+		got = append(got, p.Name)
+	}
+	assert.ElementsMatch([]string{"pod1", "pod2", "pod3"}, got)
+}
+
+func TestMultiNamespaceRetrieverWatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := fake.NewSimpleClientset()
+	ret := retrieve.MultiNamespace([]string{"ns1", "ns2"}, func(ns string) controller.TypedRetriever[*corev1.Pod] {
+		return retrieve.Pods(cli, retrieve.Options{Namespace: ns})
+	})
+
+	w, err := ret.Watch(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+
+	for _, p := range []*corev1.Pod{newPod("ns1", "pod1", nil), newPod("ns3", "pod2", nil), newPod("ns2", "pod3", nil)} {
+		_, err := cli.CoreV1().Pods(p.Namespace).Create(context.TODO(), p, metav1.CreateOptions{})
+		require.NoError(err)
+	}
+
+	got := []string{}
+	for len(got) < 2 {
+		select {
+		case ev := <-w.ResultChan():
+			got = append(got, ev.Object.(*corev1.Pod).Name)
+		case <-time.After(time.Second):
+			require.Fail("timeout waiting for watch events")
+		}
+	}
+	assert.ElementsMatch([]string{"pod1", "pod3"}, got)
+
+	// Once stopped, the watch should end.
+	w.Stop()
+	for range w.ResultChan() {
+	}
+}
+
+// rvRecorderRetriever is a retriever that records the resource versions requested on the watches.
+type rvRecorderRetriever struct {
+	listRV string
+	events []watch.Event
+
+	mu      sync.Mutex
+	watchRV []string
+}
+
+func (r *rvRecorderRetriever) List(_ context.Context, _ metav1.ListOptions) (runtime.Object, error) {
+	return &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: r.listRV}}, nil
+}
+
+func (r *rvRecorderRetriever) Watch(_ context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	r.mu.Lock()
+	r.watchRV = append(r.watchRV, options.ResourceVersion)
+	r.mu.Unlock()
+
+	fw := watch.NewFakeWithChanSize(len(r.events), false)
+	for _, ev := range r.events {
+		fw.Action(ev.Type, ev.Object)
+	}
+	return fw, nil
+}
+
+func TestMultiNamespaceRetrieverResourceVersions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	podRV := func(ns, rv string) *corev1.Pod {
+		p := newPod(ns, "test", nil)
+		p.ResourceVersion = rv
+		return p
+	}
+	rets := map[string]*rvRecorderRetriever{
+		"ns1": {listRV: "10", events: []watch.Event{{Type: watch.Added, Object: podRV("ns1", "11")}}},
+		"ns2": {listRV: "20"},
+	}
+	ret := retrieve.MultiNamespace([]string{"ns1", "ns2"}, func(ns string) controller.TypedRetriever[*corev1.Pod] {
+		return controller.NewTypedRetriever[*corev1.Pod](rets[ns])
+	})
+
+	// List and watch from the list resource version.
+	obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+	listRV := obj.(*corev1.PodList).ResourceVersion
+
+	w, err := ret.Watch(context.TODO(), metav1.ListOptions{ResourceVersion: listRV})
+	require.NoError(err)
+	ev := <-w.ResultChan()
+	w.Stop()
+	for range w.ResultChan() {
+	}
+
+	// Watch again from the last received object resource version (like the informers do).
+	w, err = ret.Watch(context.TODO(), metav1.ListOptions{ResourceVersion: ev.Object.(*corev1.Pod).ResourceVersion})
+	require.NoError(err)
+	w.Stop()
+
+	assert.Equal([]string{"10", "11"}, rets["ns1"].watchRV)
+	assert.Equal([]string{"20", "20"}, rets["ns2"].watchRV)
+}
@@ -0,0 +1,72 @@
+// Package retrieve has helpers to create controller retrievers from the Kubernetes clients
+// without the list and watch boilerplate.
+package retrieve
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+// Options are the options of the retrievers.
+type Options struct {
+	// Namespace is the namespace of the retrieved objects, by default all namespaces.
+	// Ignored on cluster scoped resources.
+	Namespace string
+	// LabelSelector restricts the retrieved objects to the ones that match the label selector (e.g `app=my-app`).
+	LabelSelector string
+	// FieldSelector restricts the retrieved objects to the ones that match the field selector (e.g `spec.nodeName=node1`).
+	FieldSelector string
+	// PageSize is the max number of objects that a list request will return, the rest will be
+	// retrieved with more list requests. By default the controller informer page size.
+	PageSize int64
+}
+
+func (o Options) listOptions(options metav1.ListOptions) metav1.ListOptions {
+	if o.LabelSelector != "" {
+		options.LabelSelector = o.LabelSelector
+	}
+
+	if o.FieldSelector != "" {
+		options.FieldSelector = o.FieldSelector
+	}
+
+	return options
+}
+
+// ListerWatcher is a Kubernetes client of a resource (e.g `CoreV1().Pods(namespace)`), L is the resource
+// list type (e.g `*corev1.PodList`).
+type ListerWatcher[L runtime.Object] interface {
+	List(ctx context.Context, options metav1.ListOptions) (L, error)
+	Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error)
+}
+
+// FromClient returns a retriever of T type objects using a Kubernetes client of the resource, the
+// namespace of the retrieved objects will be the one of the client (`Options.Namespace` is ignored).
+//
+//	ret := retrieve.FromClient[*corev1.Pod](k8scli.CoreV1().Pods(""), retrieve.Options{})
+func FromClient[T runtime.Object, L runtime.Object](client ListerWatcher[L], opts Options) controller.TypedRetriever[T] {
+	return controller.NewTypedRetriever[T](clientRetriever[L]{client: client, opts: opts})
+}
+
+type clientRetriever[L runtime.Object] struct {
+	client ListerWatcher[L]
+	opts   Options
+}
+
+func (c clientRetriever[L]) List(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+	options = c.opts.listOptions(options)
+	if c.opts.PageSize > 0 {
+		options.Limit = c.opts.PageSize
+	}
+
+	return c.client.List(ctx, options)
+}
+
+func (c clientRetriever[L]) Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	return c.client.Watch(ctx, c.opts.listOptions(options))
+}
@@ -0,0 +1,102 @@
+package retrieve_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/spotahome/kooper/v2/controller/retrieve"
+)
+
+func newPod(ns, name string, lbls map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: lbls}}
+}
+
+func TestPodsRetrieverList(t *testing.T) {
+	pods := []runtime.Object{
+		newPod("ns1", "pod1", map[string]string{"app": "app1"}),
+		newPod("ns1", "pod2", map[string]string{"app": "app2"}),
+		newPod("ns2", "pod3", map[string]string{"app": "app1"}),
+	}
+
+	tests := map[string]struct {
+		opts       retrieve.Options
+		expPods    []string
+		expOptions metav1.ListOptions
+	}{
+		"Without options it should list all the pods.": {
+			opts:    retrieve.Options{},
+			expPods: []string{"pod1", "pod2", "pod3"},
+		},
+
+		"With namespace it should list the pods of the namespace.": {
+			opts:    retrieve.Options{Namespace: "ns1"},
+			expPods: []string{"pod1", "pod2"},
+		},
+
+		"With label selector it should list the pods that match the selector.": {
+			opts:       retrieve.Options{LabelSelector: "app=app1"},
+			expPods:    []string{"pod1", "pod3"},
+			expOptions: metav1.ListOptions{LabelSelector: "app=app1"},
+		},
+
+		"With field selector and page size it should set them on the list options.": {
+			opts:       retrieve.Options{FieldSelector: "spec.nodeName=node1", PageSize: 42},
+			expPods:    []string{"pod1", "pod2", "pod3"},
+			expOptions: metav1.ListOptions{FieldSelector: "spec.nodeName=node1", Limit: 42},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			cli := fake.NewSimpleClientset(pods...)
+			var gotOptions metav1.ListOptions
+			cli.PrependReactor("list", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				la := action.(kubetesting.ListActionImpl)
+				gotOptions = la.ListOptions
+				return false, nil, nil
+			})
+
+			ret := retrieve.Pods(cli, test.opts)
+			obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+			require.NoError(err)
+
+			got := []string{}
+			for _, p := range obj.(*corev1.PodList).Items {
+				got = append(got, p.Name)
+			}
+			assert.ElementsMatch(test.expPods, got)
+			assert.Equal(test.expOptions.LabelSelector, gotOptions.LabelSelector)
+			assert.Equal(test.expOptions.FieldSelector, gotOptions.FieldSelector)
+			assert.Equal(test.expOptions.Limit, gotOptions.Limit)
+		})
+	}
+}
+
+func TestPodsRetrieverWatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cli := fake.NewSimpleClientset()
+	ret := retrieve.Pods(cli, retrieve.Options{Namespace: "ns1"})
+
+	w, err := ret.Watch(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+	defer w.Stop()
+
+	_, err = cli.CoreV1().Pods("ns1").Create(context.TODO(), newPod("ns1", "pod1", nil), metav1.CreateOptions{})
+	require.NoError(err)
+
+	ev := <-w.ResultChan()
+	assert.Equal("pod1", ev.Object.(*corev1.Pod).Name)
+}
@@ -0,0 +1,169 @@
+package retrieve
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+// NewMeasured returns a retriever that measures the latency and errors of the list and watch
+// operations of the received retriever using the metrics recorder.
+func NewMeasured(name string, mrec controller.MetricsRecorder, next controller.Retriever) controller.Retriever {
+	return measuredRetriever{name: name, mrec: mrec, next: next}
+}
+
+type measuredRetriever struct {
+	name string
+	mrec controller.MetricsRecorder
+	next controller.Retriever
+}
+
+func (m measuredRetriever) List(ctx context.Context, options metav1.ListOptions) (_ runtime.Object, err error) {
+	defer func(t0 time.Time) {
+		m.mrec.ObserveRetrieverOperationDuration(ctx, m.name, "list", err == nil, t0)
+	}(time.Now())
+
+	return m.next.List(ctx, options)
+}
+
+func (m measuredRetriever) Watch(ctx context.Context, options metav1.ListOptions) (_ watch.Interface, err error) {
+	defer func(t0 time.Time) {
+		m.mrec.ObserveRetrieverOperationDuration(ctx, m.name, "watch", err == nil, t0)
+	}(time.Now())
+
+	return m.next.Watch(ctx, options)
+}
+
+// NewLogged returns a retriever that logs the list and watch operations of the received retriever,
+// the watches after the first one are logged as restarts.
+func NewLogged(logger log.Logger, next controller.Retriever) controller.Retriever {
+	return &loggedRetriever{
+		logger: logger.WithKV(log.KV{"service": "kooper.retriever"}),
+		next:   next,
+	}
+}
+
+type loggedRetriever struct {
+	logger log.Logger
+	next   controller.Retriever
+
+	mu      sync.Mutex
+	watches int
+}
+
+func (l *loggedRetriever) List(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+	obj, err := l.next.List(ctx, options)
+	if err != nil {
+		l.logger.Errorf("could not list from resource version %q: %s", options.ResourceVersion, err)
+		return nil, err
+	}
+	l.logger.Debugf("listed from resource version %q", options.ResourceVersion)
+
+	return obj, nil
+}
+
+func (l *loggedRetriever) Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	l.mu.Lock()
+	l.watches++
+	restart := l.watches > 1
+	l.mu.Unlock()
+
+	w, err := l.next.Watch(ctx, options)
+	if err != nil {
+		l.logger.Errorf("could not watch from resource version %q: %s", options.ResourceVersion, err)
+		return nil, err
+	}
+
+	if restart {
+		l.logger.Infof("watch restarted from resource version %q", options.ResourceVersion)
+	} else {
+		l.logger.Debugf("watch started from resource version %q", options.ResourceVersion)
+	}
+
+	return w, nil
+}
+
+// NewTransformed returns a retriever that transforms the objects of the received retriever before they
+// are stored on the controller cache (e.g remove the fields that are not used by the handlers to reduce
+// the memory, e.g `controller.TransformStripManagedFields`). The transform is applied to the list items and
+// to the watch events objects. Prefer `controller.Config.ObjectTransform` when possible.
+//
+// If the transform of a watch event object fails, the event will be delivered with the original object.
+func NewTransformed(transform cache.TransformFunc, next controller.Retriever) controller.Retriever {
+	return transformedRetriever{transform: transform, next: next}
+}
+
+type transformedRetriever struct {
+	transform cache.TransformFunc
+	next      controller.Retriever
+}
+
+func (t transformedRetriever) transformObject(obj runtime.Object) (runtime.Object, error) {
+	tobj, err := t.transform(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	robj, ok := tobj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("transformed object is not a runtime.Object: %T", tobj)
+	}
+
+	return robj, nil
+}
+
+func (t transformedRetriever) List(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+	list, err := t.next.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract list items: %w", err)
+	}
+
+	for i, item := range items {
+		items[i], err = t.transformObject(item)
+		if err != nil {
+			return nil, fmt.Errorf("could not transform list item: %w", err)
+		}
+	}
+
+	err = meta.SetList(list, items)
+	if err != nil {
+		return nil, fmt.Errorf("could not set list items: %w", err)
+	}
+
+	return list, nil
+}
+
+func (t transformedRetriever) Watch(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+	w, err := t.next.Watch(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(ev watch.Event) (watch.Event, bool) {
+		if ev.Type == watch.Error || ev.Type == watch.Bookmark {
+			return ev, true
+		}
+
+		obj, err := t.transformObject(ev.Object)
+		if err == nil {
+			ev.Object = obj
+		}
+
+		return ev, true
+	}), nil
+}
@@ -0,0 +1,125 @@
+package retrieve_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/retrieve"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+type retrieverMetricsRecorder struct {
+	controller.MetricsRecorder
+	mu  sync.Mutex
+	ops []string
+}
+
+func (r *retrieverMetricsRecorder) ObserveRetrieverOperationDuration(_ context.Context, retriever string, operation string, success bool, _ time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := "ok"
+	if !success {
+		status = "error"
+	}
+	r.ops = append(r.ops, retriever+"/"+operation+"/"+status)
+}
+
+func TestMeasuredRetriever(t *testing.T) {
+	assert := assert.New(t)
+
+	cli := fake.NewSimpleClientset(newPod("ns1", "pod1", nil))
+	cli.PrependReactor("list", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "ns2" {
+			return true, nil, errors.New("wanted error")
+		}
+		return false, nil, nil
+	})
+
+	mrec := &retrieverMetricsRecorder{MetricsRecorder: controller.DummyMetricsRecorder}
+	ret1 := retrieve.NewMeasured("ret1", mrec, retrieve.Pods(cli, retrieve.Options{Namespace: "ns1"}))
+	ret2 := retrieve.NewMeasured("ret2", mrec, retrieve.Pods(cli, retrieve.Options{Namespace: "ns2"}))
+
+	_, err := ret1.List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(err)
+	w, err := ret1.Watch(context.TODO(), metav1.ListOptions{})
+	if assert.NoError(err) {
+		w.Stop()
+	}
+	_, err = ret2.List(context.TODO(), metav1.ListOptions{})
+	assert.Error(err)
+
+	assert.Equal([]string{"ret1/list/ok", "ret1/watch/ok", "ret2/list/error"}, mrec.ops)
+}
+
+func TestLoggedRetriever(t *testing.T) {
+	assert := assert.New(t)
+
+	cli := fake.NewSimpleClientset(newPod("ns1", "pod1", nil))
+	ret := retrieve.NewLogged(log.Dummy, retrieve.Pods(cli, retrieve.Options{}))
+
+	obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+	if assert.NoError(err) {
+		assert.Len(obj.(*corev1.PodList).Items, 1)
+	}
+
+	for i := 0; i < 2; i++ {
+		w, err := ret.Watch(context.TODO(), metav1.ListOptions{})
+		if assert.NoError(err) {
+			w.Stop()
+		}
+	}
+}
+
+func TestTransformedRetriever(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	removeLabels := func(obj interface{}) (interface{}, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return nil, errors.New("not a pod")
+		}
+		pod.Labels = nil
+		return pod, nil
+	}
+
+	cli := fake.NewSimpleClientset(newPod("ns1", "pod1", map[string]string{"k": "v"}))
+	ret := retrieve.NewTransformed(removeLabels, retrieve.Pods(cli, retrieve.Options{}))
+
+	// List.
+	obj, err := ret.List(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+	pods := obj.(*corev1.PodList).Items
+	require.Len(pods, 1)
+	assert.Equal("pod1", pods[0].Name)
+	assert.Nil(pods[0].Labels)
+
+	// Watch.
+	w, err := ret.Watch(context.TODO(), metav1.ListOptions{})
+	require.NoError(err)
+	defer w.Stop()
+
+	_, err = cli.CoreV1().Pods("ns1").Create(context.TODO(), newPod("ns1", "pod2", map[string]string{"k": "v"}), metav1.CreateOptions{})
+	require.NoError(err)
+
+	select {
+	case ev := <-w.ResultChan():
+		pod := ev.Object.(*corev1.Pod)
+		assert.Equal("pod2", pod.Name)
+		assert.Nil(pod.Labels)
+	case <-time.After(time.Second):
+		require.Fail("timeout waiting for watch events")
+	}
+}
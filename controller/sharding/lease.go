@@ -0,0 +1,254 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/spotahome/kooper/v2/log"
+)
+
+const (
+	// GroupLabel is the label set on the membership Leases with the sharding group key.
+	GroupLabel = "sharding.kooper.spotahome.com/group"
+
+	defLeaseDuration = 15 * time.Second
+	defRenewPeriod   = 2 * time.Second
+)
+
+// LeaseConfig is the configuration of the Lease based sharder.
+type LeaseConfig struct {
+	// Key is the sharding group, all the replicas of the same controller must use the same key.
+	Key string
+	// Namespace is the namespace where the membership Leases will be created.
+	Namespace string
+	// KubernetesClient is the Kubernetes client used to manage the Leases.
+	KubernetesClient kubernetes.Interface
+	// Identity is the identity of the replica, it must be unique between the replicas (e.g the pod
+	// name using the downward API). By default it will use the hostname with a random suffix.
+	Identity string
+	// LeaseDuration is the duration a replica is a member since its last renewal, when a replica
+	// dies, its keys will be rebalanced after this duration.
+	LeaseDuration time.Duration
+	// RenewPeriod is the interval used to renew the replica membership and check the rest of the members.
+	RenewPeriod time.Duration
+	// Logger will log messages of the sharder.
+	Logger log.Logger
+}
+
+func (c *LeaseConfig) setDefaults() error {
+	if c.Key == "" {
+		return fmt.Errorf("a key is required")
+	}
+
+	if c.Namespace == "" {
+		return fmt.Errorf("a namespace is required")
+	}
+
+	if c.KubernetesClient == nil {
+		return fmt.Errorf("a Kubernetes client is required")
+	}
+
+	if c.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("could not get hostname for the identity: %w", err)
+		}
+		c.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = defLeaseDuration
+	}
+
+	if c.RenewPeriod <= 0 {
+		c.RenewPeriod = defRenewPeriod
+	}
+
+	if c.RenewPeriod >= c.LeaseDuration {
+		return fmt.Errorf("renew period must be lower than the lease duration")
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.NewStd(false)
+		c.Logger.Warningf("no logger specified, fallback to default logger, to disable logging use a explicit Noop logger")
+	}
+	c.Logger = c.Logger.WithKV(log.KV{
+		"source-service": "kooper/sharding",
+		"sharding-id":    fmt.Sprintf("%s/%s", c.Namespace, c.Key),
+	})
+
+	return nil
+}
+
+// leaseSharder is a sharder that uses a Lease per replica to know the members of the group.
+type leaseSharder struct {
+	cfg       LeaseConfig
+	leaseName string
+	logger    log.Logger
+
+	mu        sync.RWMutex
+	members   []string
+	lastRenew time.Time
+}
+
+// NewLease returns a new sharder that coordinates the replicas using Kubernetes Leases. Each replica
+// renews its own Lease, and the keys are split between the replicas that have a valid Lease.
+func NewLease(cfg LeaseConfig) (Sharder, error) {
+	if err := cfg.setDefaults(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &leaseSharder{
+		cfg:       cfg,
+		leaseName: fmt.Sprintf("%s-%x", cfg.Key, weight(cfg.Identity, "")),
+		logger:    cfg.Logger,
+	}, nil
+}
+
+func (l *leaseSharder) Owns(key string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return owner(l.members, key) == l.cfg.Identity
+}
+
+func (l *leaseSharder) Run(ctx context.Context, onChange func(gained func(key string) bool)) error {
+	l.logger.Infof("running in sharding mode, joining the sharding group...")
+
+	// Leave the group when stopping so the rest of the members don't need to wait the lease expiration.
+	defer func() {
+		l.setMembers(nil)
+		err := l.cfg.KubernetesClient.CoordinationV1().Leases(l.cfg.Namespace).Delete(context.Background(), l.leaseName, metav1.DeleteOptions{})
+		if err != nil && !kubeerrors.IsNotFound(err) {
+			l.logger.Warningf("could not delete sharding membership lease: %s", err)
+		}
+	}()
+
+	t := time.NewTicker(l.cfg.RenewPeriod)
+	defer t.Stop()
+	for {
+		if err := l.sync(ctx, onChange); err != nil {
+			l.logger.Warningf("could not sync sharding members: %s", err)
+
+			// If we can't renew our membership, the other members will take our keys.
+			if time.Since(l.lastRenew) > l.cfg.LeaseDuration {
+				if _, changed := l.setMembers(nil); changed {
+					l.logger.Warningf("sharding membership expired, not owning any key")
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// sync renews the replica membership and updates the members of the group.
+func (l *leaseSharder) sync(ctx context.Context, onChange func(gained func(key string) bool)) error {
+	now := time.Now()
+	if err := l.renew(ctx, now); err != nil {
+		return fmt.Errorf("could not renew membership: %w", err)
+	}
+	l.lastRenew = now
+
+	leases, err := l.cfg.KubernetesClient.CoordinationV1().Leases(l.cfg.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", GroupLabel, l.cfg.Key),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list members: %w", err)
+	}
+
+	members := []string{}
+	for _, lease := range leases.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+
+		expiration := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expiration) {
+			// The member is dead, clean its Lease (best effort, other members could have removed it).
+			_ = l.cfg.KubernetesClient.CoordinationV1().Leases(l.cfg.Namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion},
+			})
+			continue
+		}
+
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	slices.Sort(members)
+
+	prevMembers, changed := l.setMembers(members)
+	if !changed {
+		return nil
+	}
+
+	l.logger.Infof("sharding members changed: %v", members)
+	if onChange != nil {
+		onChange(func(key string) bool {
+			return owner(members, key) == l.cfg.Identity && owner(prevMembers, key) != l.cfg.Identity
+		})
+	}
+
+	return nil
+}
+
+func (l *leaseSharder) renew(ctx context.Context, now time.Time) error {
+	renewTime := metav1.NewMicroTime(now)
+	leaseDuration := int32(l.cfg.LeaseDuration.Round(time.Second) / time.Second)
+	if leaseDuration < 1 {
+		leaseDuration = 1
+	}
+
+	cli := l.cfg.KubernetesClient.CoordinationV1().Leases(l.cfg.Namespace)
+	lease, err := cli.Get(ctx, l.leaseName, metav1.GetOptions{})
+	if err != nil {
+		if !kubeerrors.IsNotFound(err) {
+			return err
+		}
+
+		_, err := cli.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      l.leaseName,
+				Namespace: l.cfg.Namespace,
+				Labels:    map[string]string{GroupLabel: l.cfg.Key},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.cfg.Identity,
+				LeaseDurationSeconds: &leaseDuration,
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &l.cfg.Identity
+	lease.Spec.LeaseDurationSeconds = &leaseDuration
+	lease.Spec.RenewTime = &renewTime
+	_, err = cli.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// setMembers sets the group members and returns the previous members and true if they changed.
+func (l *leaseSharder) setMembers(members []string) (prevMembers []string, changed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prevMembers = l.members
+	if slices.Equal(prevMembers, members) {
+		return prevMembers, false
+	}
+	l.members = members
+	return prevMembers, true
+}
@@ -0,0 +1,196 @@
+package sharding_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/spotahome/kooper/v2/controller/sharding"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+func TestNewLease(t *testing.T) {
+	tests := map[string]struct {
+		cfg    sharding.LeaseConfig
+		expErr bool
+	}{
+		"A valid configuration should not fail.": {
+			cfg: sharding.LeaseConfig{Key: "test", Namespace: "default", KubernetesClient: fake.NewSimpleClientset()},
+		},
+
+		"A missing key should fail.": {
+			cfg:    sharding.LeaseConfig{Namespace: "default", KubernetesClient: fake.NewSimpleClientset()},
+			expErr: true,
+		},
+
+		"A missing namespace should fail.": {
+			cfg:    sharding.LeaseConfig{Key: "test", KubernetesClient: fake.NewSimpleClientset()},
+			expErr: true,
+		},
+
+		"A missing Kubernetes client should fail.": {
+			cfg:    sharding.LeaseConfig{Key: "test", Namespace: "default"},
+			expErr: true,
+		},
+
+		"A renew period greater than the lease duration should fail.": {
+			cfg: sharding.LeaseConfig{
+				Key:              "test",
+				Namespace:        "default",
+				KubernetesClient: fake.NewSimpleClientset(),
+				LeaseDuration:    time.Second,
+				RenewPeriod:      2 * time.Second,
+			},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := test.cfg
+			cfg.Logger = log.Dummy
+			_, err := sharding.NewLease(cfg)
+
+			if test.expErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// assertOwnership asserts that every key is owned by only one of the sharders.
+func assertOwnership(t *testing.T, keys []string, sharders []sharding.Sharder) bool {
+	t.Helper()
+	for _, key := range keys {
+		owners := 0
+		for _, s := range sharders {
+			if s.Owns(key) {
+				owners++
+			}
+		}
+		if owners != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLeaseSharder(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	cli := fake.NewSimpleClientset()
+	keys := []string{}
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("ns%d/obj%d", i%3, i))
+	}
+
+	// Start the replicas, recording the keys gained by each replica on the ownership changes.
+	sharders := []sharding.Sharder{}
+	cancels := []context.CancelFunc{}
+	doneCs := []chan struct{}{}
+	var mu sync.Mutex
+	gained := []map[string]bool{}
+	for i := 0; i < 3; i++ {
+		s, err := sharding.NewLease(sharding.LeaseConfig{
+			Key:              "test",
+			Namespace:        "default",
+			KubernetesClient: cli,
+			Identity:         fmt.Sprintf("replica-%d", i),
+			LeaseDuration:    time.Second,
+			RenewPeriod:      10 * time.Millisecond,
+			Logger:           log.Dummy,
+		})
+		require.NoError(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		doneC := make(chan struct{})
+		replicaGained := map[string]bool{}
+		mu.Lock()
+		gained = append(gained, replicaGained)
+		mu.Unlock()
+		go func() {
+			defer close(doneC)
+			_ = s.Run(ctx, func(gainedF func(key string) bool) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, key := range keys {
+					if gainedF(key) {
+						replicaGained[key] = true
+					}
+				}
+			})
+		}()
+
+		sharders = append(sharders, s)
+		cancels = append(cancels, cancel)
+		doneCs = append(doneCs, doneC)
+	}
+
+	// All the replicas should own keys, and every key should be owned by a single replica.
+	require.Eventually(func() bool { return assertOwnership(t, keys, sharders) }, time.Second, 10*time.Millisecond)
+	for i, s := range sharders {
+		owned := 0
+		for _, key := range keys {
+			if s.Owns(key) {
+				owned++
+			}
+		}
+		assert.Greater(owned, 0, "replica %d should own keys", i)
+	}
+
+	// Every replica should have gained the keys it owns.
+	mu.Lock()
+	for i, s := range sharders {
+		for _, key := range keys {
+			if s.Owns(key) {
+				assert.True(gained[i][key], "replica %d should have gained its owned keys", i)
+			}
+		}
+	}
+	clear(gained[0])
+	mu.Unlock()
+
+	// Stop one of the replicas, the rest should own all the keys.
+	ownedBefore := map[string]bool{}
+	for _, key := range keys {
+		ownedBefore[key] = sharders[0].Owns(key)
+	}
+	cancels[2]()
+	<-doneCs[2]
+
+	leases, err := cli.CoordinationV1().Leases("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(err)
+	assert.Len(leases.Items, 2)
+
+	require.Eventually(func() bool { return assertOwnership(t, keys, sharders[:2]) }, time.Second, 10*time.Millisecond)
+	for _, key := range keys {
+		assert.False(sharders[2].Owns(key))
+		// The keys of the replicas that are alive should not move.
+		if ownedBefore[key] {
+			assert.True(sharders[0].Owns(key))
+		}
+	}
+
+	// The replica should only have gained the keys of the stopped replica.
+	require.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(gained[0]) > 0
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	for _, key := range keys {
+		assert.Equal(sharders[0].Owns(key) && !ownedBefore[key], gained[0][key])
+	}
+	mu.Unlock()
+}
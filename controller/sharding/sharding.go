@@ -0,0 +1,48 @@
+// Package sharding splits the keys handled by a controller between multiple replicas, so all
+// the replicas handle events at the same time, instead of having a single leader.
+package sharding
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// Sharder knows the keys that belong to the instance shard.
+//
+// The ownership is eventually consistent, during a rebalance two replicas could handle the
+// same key, so the handlers should be idempotent.
+type Sharder interface {
+	// Owns returns true if the key (`{namespace}/{name}`) belongs to the instance shard.
+	Owns(key string) bool
+	// Run runs the sharder membership until the context is cancelled, it's a blocking action.
+	// onChange will be called every time the owned keys change (e.g a replica joins or leaves) with
+	// a function that returns true if the key has moved to the instance shard on the change, this way
+	// only the gained keys need to be handled.
+	Run(ctx context.Context, onChange func(gained func(key string) bool)) error
+}
+
+// owner returns the member that owns the key using rendezvous hashing (highest random weight),
+// this way when a member joins or leaves only the keys of that member are moved.
+func owner(members []string, key string) string {
+	var (
+		owner     string
+		maxWeight uint64
+	)
+	for _, m := range members {
+		w := weight(m, key)
+		if owner == "" || w > maxWeight || (w == maxWeight && m > owner) {
+			owner = m
+			maxWeight = w
+		}
+	}
+
+	return owner
+}
+
+func weight(member, key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
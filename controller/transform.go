@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// LastAppliedConfigAnnotation is the annotation where `kubectl apply` stores the last applied object.
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// TransformStripManagedFields is an object transform (check `Config.ObjectTransform`) that removes the
+// managed fields and the `kubectl apply` last applied configuration annotation of the objects. These
+// are usually not used by the handlers and they can be a big part of the objects size.
+func TransformStripManagedFields(obj interface{}) (interface{}, error) {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		// Not an object with meta (e.g a tombstone), don't transform.
+		return obj, nil
+	}
+
+	m.SetManagedFields(nil)
+
+	annotations := m.GetAnnotations()
+	if _, ok := annotations[LastAppliedConfigAnnotation]; ok {
+		delete(annotations, LastAppliedConfigAnnotation)
+		m.SetAnnotations(annotations)
+	}
+
+	return obj, nil
+}
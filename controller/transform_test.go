@@ -0,0 +1,52 @@
+package controller_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+func TestTransformStripManagedFields(t *testing.T) {
+	tests := map[string]struct {
+		obj    interface{}
+		expObj interface{}
+	}{
+		"Objects should have the managed fields and last applied configuration removed.": {
+			obj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+				Annotations: map[string]string{
+					"k":                                    "v",
+					controller.LastAppliedConfigAnnotation: "{}",
+				},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+			}},
+			expObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{"k": "v"},
+			}},
+		},
+
+		"Objects without managed fields should not be changed.": {
+			obj:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+			expObj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+		},
+
+		"Objects without meta should be ignored.": {
+			obj:    "test",
+			expObj: "test",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotObj, err := controller.TransformStripManagedFields(test.obj)
+			require.NoError(t, err)
+			assert.Equal(t, test.expObj, gotObj)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ErrUnexpectedType will be used when a typed controller receives an object of a type that is not the expected one.
+var ErrUnexpectedType = errors.New("unexpected object type")
+
+// TypedHandler knows how to handle the received resources of a specific type from a kubernetes cluster.
+type TypedHandler[T runtime.Object] interface {
+	Handle(context.Context, T) error
+}
+
+// TypedHandlerFunc knows how to handle resources of a specific type.
+type TypedHandlerFunc[T runtime.Object] func(context.Context, T) error
+
+// Handle satisfies controller.TypedHandler interface.
+func (h TypedHandlerFunc[T]) Handle(ctx context.Context, obj T) error {
+	if h == nil {
+		return fmt.Errorf("handle func is required")
+	}
+	return h(ctx, obj)
+}
+
+// TypedRetriever is a Retriever that retrieves objects of the T type. The type is only used
+// to check at compile time that the retriever and the handler of a typed controller match.
+type TypedRetriever[T runtime.Object] interface {
+	Retriever
+	typed(T)
+}
+
+type typedRetriever[T runtime.Object] struct {
+	Retriever
+}
+
+func (typedRetriever[T]) typed(T) {}
+
+// NewTypedRetriever returns a TypedRetriever from a Retriever that retrieves objects of the T type.
+func NewTypedRetriever[T runtime.Object](r Retriever) TypedRetriever[T] {
+	return typedRetriever[T]{Retriever: r}
+}
+
+// TypedConfig is the typed controller configuration.
+//
+// The handlers and the retriever are typed, the rest of the configuration is the same one as
+// the regular controller configuration, the regular `Handler`, `DeleteHandler` and `Retriever`
+// will be ignored.
+type TypedConfig[T runtime.Object] struct {
+	Config
+
+	// Handler is the controller typed handler.
+	Handler TypedHandler[T]
+	// DeleteHandler is the optional controller typed delete handler (check Config.DeleteHandler).
+	DeleteHandler TypedHandler[T]
+	// Retriever is the controller typed retriever.
+	Retriever TypedRetriever[T]
+}
+
+// NewTyped creates a new controller that handles objects of the T type, the objects are converted
+// to the T type before calling the typed handlers, if the conversion is not possible the
+// processing will end with a permanent `ErrUnexpectedType` error.
+func NewTyped[T runtime.Object](cfg *TypedConfig[T]) (Controller, error) {
+	if cfg.Handler == nil {
+		return nil, fmt.Errorf("could no create controller: %w: a handler is required", ErrControllerNotValid)
+	}
+
+	if cfg.Retriever == nil {
+		return nil, fmt.Errorf("could no create controller: %w: a retriever is required", ErrControllerNotValid)
+	}
+
+	cfg.Config.Handler = newTypedHandler(cfg.Handler)
+	cfg.Config.DeleteHandler = nil
+	if cfg.DeleteHandler != nil {
+		cfg.Config.DeleteHandler = newTypedHandler(cfg.DeleteHandler)
+	}
+	cfg.Config.Retriever = cfg.Retriever
+
+	return New(&cfg.Config)
+}
+
+func newTypedHandler[T runtime.Object](h TypedHandler[T]) Handler {
+	return HandlerFunc(func(ctx context.Context, obj runtime.Object) error {
+		tobj, ok := obj.(T)
+		if !ok {
+			var exp T
+			return Permanent(fmt.Errorf("%w: expected %T, got %T", ErrUnexpectedType, exp, obj))
+		}
+
+		return h.Handle(ctx, tobj)
+	})
+}
+
+// TypedLister is a Lister of objects of the T type (check Lister).
+type TypedLister[T runtime.Object] interface {
+	Get(namespace, name string) (obj T, exists bool, err error)
+	List(selector labels.Selector) ([]T, error)
+	ListNamespace(namespace string, selector labels.Selector) ([]T, error)
+	ByIndex(indexName, indexedValue string) ([]T, error)
+}
+
+type typedLister[T runtime.Object] struct {
+	lister Lister
+}
+
+// NewTypedLister returns a TypedLister from a Lister of objects of the T type.
+func NewTypedLister[T runtime.Object](l Lister) TypedLister[T] {
+	return typedLister[T]{lister: l}
+}
+
+// TypedListerFromContext returns the TypedLister of the controller local cache (check `ListerFromContext`).
+// If the context doesn't have a Lister it will return nil.
+func TypedListerFromContext[T runtime.Object](ctx context.Context) TypedLister[T] {
+	l := ListerFromContext(ctx)
+	if l == nil {
+		return nil
+	}
+	return NewTypedLister[T](l)
+}
+
+func (t typedLister[T]) Get(namespace, name string) (T, bool, error) {
+	var empty T
+	obj, exists, err := t.lister.Get(namespace, name)
+	if err != nil || !exists {
+		return empty, exists, err
+	}
+
+	tobj, ok := obj.(T)
+	if !ok {
+		return empty, false, fmt.Errorf("%w: expected %T, got %T", ErrUnexpectedType, empty, obj)
+	}
+
+	return tobj, true, nil
+}
+
+func (t typedLister[T]) List(selector labels.Selector) ([]T, error) {
+	objs, err := t.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	return typedObjects[T](objs)
+}
+
+func (t typedLister[T]) ListNamespace(namespace string, selector labels.Selector) ([]T, error) {
+	objs, err := t.lister.ListNamespace(namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	return typedObjects[T](objs)
+}
+
+func (t typedLister[T]) ByIndex(indexName, indexedValue string) ([]T, error) {
+	objs, err := t.lister.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, err
+	}
+	return typedObjects[T](objs)
+}
+
+func typedObjects[T runtime.Object](objs []runtime.Object) ([]T, error) {
+	tobjs := make([]T, 0, len(objs))
+	for _, obj := range objs {
+		tobj, ok := obj.(T)
+		if !ok {
+			var exp T
+			return nil, fmt.Errorf("%w: expected %T, got %T", ErrUnexpectedType, exp, obj)
+		}
+		tobjs = append(tobjs, tobj)
+	}
+
+	return tobjs, nil
+}
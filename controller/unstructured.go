@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FromUnstructured converts an unstructured object to a T typed object, the scheme must have
+// the object group version kind registered.
+func FromUnstructured[T runtime.Object](scheme *runtime.Scheme, u *unstructured.Unstructured) (T, error) {
+	var empty T
+
+	obj, err := scheme.New(u.GroupVersionKind())
+	if err != nil {
+		return empty, fmt.Errorf("could not create object: %w", err)
+	}
+
+	tobj, ok := obj.(T)
+	if !ok {
+		return empty, fmt.Errorf("%w: expected %T, got %T", ErrUnexpectedType, empty, obj)
+	}
+
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), tobj)
+	if err != nil {
+		return empty, fmt.Errorf("could not convert unstructured object: %w", err)
+	}
+
+	return tobj, nil
+}
+
+// ToUnstructured converts an object to an unstructured object.
+func ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert object to unstructured: %w", err)
+	}
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// NewUnstructuredHandler returns a handler of unstructured objects (e.g from `retrieve.Dynamic`) that
+// converts the objects to the T type using the scheme before calling the typed handler. If the
+// conversion is not possible the processing will end with a permanent error.
+func NewUnstructuredHandler[T runtime.Object](scheme *runtime.Scheme, h TypedHandler[T]) TypedHandler[*unstructured.Unstructured] {
+	return TypedHandlerFunc[*unstructured.Unstructured](func(ctx context.Context, u *unstructured.Unstructured) error {
+		tobj, err := FromUnstructured[T](scheme, u)
+		if err != nil {
+			return Permanent(err)
+		}
+
+		return h.Handle(ctx, tobj)
+	})
+}
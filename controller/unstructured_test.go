@@ -0,0 +1,89 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+func newUnstructuredPod(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "ns1",
+		},
+		"spec": map[string]interface{}{
+			"nodeName": "node1",
+		},
+	}}
+}
+
+func TestFromUnstructured(t *testing.T) {
+	t.Run("Converting an unstructured object to its type should convert it.", func(t *testing.T) {
+		pod, err := controller.FromUnstructured[*corev1.Pod](scheme.Scheme, newUnstructuredPod("pod1"))
+		require.NoError(t, err)
+		assert.Equal(t, "pod1", pod.Name)
+		assert.Equal(t, "ns1", pod.Namespace)
+		assert.Equal(t, "node1", pod.Spec.NodeName)
+	})
+
+	t.Run("Converting an unstructured object to a different type should fail.", func(t *testing.T) {
+		_, err := controller.FromUnstructured[*appsv1.Deployment](scheme.Scheme, newUnstructuredPod("pod1"))
+		assert.ErrorIs(t, err, controller.ErrUnexpectedType)
+	})
+
+	t.Run("Converting an unstructured object not registered on the scheme should fail.", func(t *testing.T) {
+		u := newUnstructuredPod("pod1")
+		u.SetAPIVersion("example.com/v1")
+		_, err := controller.FromUnstructured[*corev1.Pod](scheme.Scheme, u)
+		assert.Error(t, err)
+	})
+
+	t.Run("Converting an object to unstructured and back should return the same object.", func(t *testing.T) {
+		exp := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns1"},
+		}
+		u, err := controller.ToUnstructured(exp)
+		require.NoError(t, err)
+		pod, err := controller.FromUnstructured[*corev1.Pod](scheme.Scheme, u)
+		require.NoError(t, err)
+		assert.Equal(t, exp, pod)
+	})
+}
+
+func TestUnstructuredHandler(t *testing.T) {
+	t.Run("The handler should receive the typed object.", func(t *testing.T) {
+		var got *corev1.Pod
+		h := controller.NewUnstructuredHandler(scheme.Scheme, controller.TypedHandlerFunc[*corev1.Pod](func(_ context.Context, pod *corev1.Pod) error {
+			got = pod
+			return nil
+		}))
+
+		err := h.Handle(context.TODO(), newUnstructuredPod("pod1"))
+		require.NoError(t, err)
+		assert.Equal(t, "pod1", got.Name)
+	})
+
+	t.Run("The handler should return a permanent error if the object can't be converted.", func(t *testing.T) {
+		h := controller.NewUnstructuredHandler(scheme.Scheme, controller.TypedHandlerFunc[*appsv1.Deployment](func(_ context.Context, _ *appsv1.Deployment) error {
+			return errors.New("should not be called")
+		}))
+
+		err := h.Handle(context.TODO(), newUnstructuredPod("pod1"))
+		assert.True(t, controller.IsPermanent(err))
+		assert.ErrorIs(t, err, controller.ErrUnexpectedType)
+	})
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/spotahome/kooper/v2/log"
+)
+
+// EnqueueMapper knows how to map an object of a secondary resource to the keys (`{namespace}/{name}`
+// or `{name}` on cluster scoped resources) of the controller primary resource objects.
+type EnqueueMapper interface {
+	Map(ctx context.Context, obj runtime.Object) ([]string, error)
+}
+
+// EnqueueMapperFunc is a helper to create EnqueueMappers from functions.
+type EnqueueMapperFunc func(ctx context.Context, obj runtime.Object) ([]string, error)
+
+// Map satisfies controller.EnqueueMapper interface.
+func (e EnqueueMapperFunc) Map(ctx context.Context, obj runtime.Object) ([]string, error) {
+	if e == nil {
+		return nil, fmt.Errorf("map func is required")
+	}
+	return e(ctx, obj)
+}
+
+// Watch is a secondary resource that the controller will watch, the events of the secondary resource
+// objects will be mapped to keys of the primary resource and these will be enqueued on the controller,
+// so the handler will receive the primary resource objects.
+type Watch struct {
+	// Retriever is the secondary resource retriever.
+	Retriever Retriever
+	// Mapper maps the secondary resource objects to the primary resource keys.
+	Mapper EnqueueMapper
+}
+
+func (w Watch) validate() error {
+	if w.Retriever == nil {
+		return fmt.Errorf("a retriever is required")
+	}
+
+	if w.Mapper == nil {
+		return fmt.Errorf("a mapper is required")
+	}
+
+	return nil
+}
+
+// MapOwnerReference returns an EnqueueMapper that maps the objects to the keys of their owners that
+// are of the received group kind (e.g `apps/Deployment`). The owners are expected to be on the same
+// namespace as the object.
+func MapOwnerReference(gk schema.GroupKind) EnqueueMapper {
+	return EnqueueMapperFunc(func(_ context.Context, obj runtime.Object) ([]string, error) {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object has no meta: %w", err)
+		}
+
+		keys := []string{}
+		for _, or := range m.GetOwnerReferences() {
+			gv, err := schema.ParseGroupVersion(or.APIVersion)
+			if err != nil {
+				return nil, fmt.Errorf("invalid owner reference api version: %w", err)
+			}
+
+			if or.Kind != gk.Kind || gv.Group != gk.Group {
+				continue
+			}
+
+			keys = append(keys, objectKey(m.GetNamespace(), or.Name))
+		}
+
+		return keys, nil
+	})
+}
+
+// MapLabel returns an EnqueueMapper that maps the objects to the key of the object named with the
+// value of the received label. The primary object is expected to be on the same namespace as the object.
+func MapLabel(label string) EnqueueMapper {
+	return EnqueueMapperFunc(func(_ context.Context, obj runtime.Object) ([]string, error) {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("object has no meta: %w", err)
+		}
+
+		name, ok := m.GetLabels()[label]
+		if !ok || name == "" {
+			return nil, nil
+		}
+
+		return []string{objectKey(m.GetNamespace(), name)}, nil
+	})
+}
+
+func objectKey(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// newWatchEventHandler returns an informer event handler that maps the secondary resource events
+// to keys of the primary resource and adds them to the queue.
+func newWatchEventHandler(mapper EnqueueMapper, queue Queue, logger log.Logger) cache.ResourceEventHandler {
+	enqueue := func(event string, obj interface{}) {
+		// Deletes could be received as tombstones.
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+
+		robj, ok := obj.(runtime.Object)
+		if !ok {
+			logger.Warningf("could not map item from '%s' event, not a runtime.Object", event)
+			return
+		}
+
+		ctx := context.TODO()
+		keys, err := mapper.Map(ctx, robj)
+		if err != nil {
+			logger.Warningf("could not map item from '%s' event: %s", event, err)
+			return
+		}
+
+		for _, key := range keys {
+			queue.Add(ctx, key)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { enqueue("add", obj) },
+		UpdateFunc: func(old interface{}, new interface{}) {
+			// The old object could be mapped to different keys (e.g owner changed).
+			enqueue("update", old)
+			enqueue("update", new)
+		},
+		DeleteFunc: func(obj interface{}) { enqueue("delete", obj) },
+	}
+}
@@ -0,0 +1,76 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spotahome/kooper/v2/controller"
+)
+
+func TestEnqueueMappers(t *testing.T) {
+	tests := map[string]struct {
+		mapper  controller.EnqueueMapper
+		obj     runtime.Object
+		expKeys []string
+		expErr  bool
+	}{
+		"Mapping by owner reference should return the keys of the owners of the kind.": {
+			mapper: controller.MapOwnerReference(schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}),
+			obj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "ns1",
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs1"},
+					{APIVersion: "v1", Kind: "ReplicaSet", Name: "rs2"},
+					{APIVersion: "apps/v1", Kind: "Deployment", Name: "dep1"},
+					{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs3"},
+				},
+			}},
+			expKeys: []string{"ns1/rs1", "ns1/rs3"},
+		},
+
+		"Mapping by owner reference without owners should not return keys.": {
+			mapper:  controller.MapOwnerReference(schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}),
+			obj:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns1"}},
+			expKeys: []string{},
+		},
+
+		"Mapping by label should return the key of the object named by the label value.": {
+			mapper: controller.MapLabel("app"),
+			obj: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "ns1",
+				Labels:    map[string]string{"app": "app1"},
+			}},
+			expKeys: []string{"ns1/app1"},
+		},
+
+		"Mapping by label without the label should not return keys.": {
+			mapper:  controller.MapLabel("app"),
+			obj:     &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns1"}},
+			expKeys: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			gotKeys, err := test.mapper.Map(context.TODO(), test.obj)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				require.Equal(test.expKeys, gotKeys)
+			}
+		})
+	}
+}
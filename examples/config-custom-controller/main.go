@@ -9,17 +9,14 @@ import (
 
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 
 	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/retrieve"
 	"github.com/spotahome/kooper/v2/log"
 	kooperlogrus "github.com/spotahome/kooper/v2/log/logrus"
 )
@@ -45,34 +42,28 @@ func run() error {
 	}
 
 	// Create our retriever so the controller knows how to get/listen for pod events.
-	retr := controller.MustRetrieverFromListerWatcher(&cache.ListWatch{
-		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-			return k8scli.CoreV1().Pods("").List(context.Background(), options)
-		},
-		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-			return k8scli.CoreV1().Pods("").Watch(context.Background(), options)
-		},
-	})
+	retr := retrieve.Pods(k8scli, retrieve.Options{})
 
 	// Our domain logic that will print every add/sync/update and delete event we .
-	hand := controller.HandlerFunc(func(_ context.Context, obj runtime.Object) error {
-		pod := obj.(*corev1.Pod)
+	hand := controller.TypedHandlerFunc[*corev1.Pod](func(_ context.Context, pod *corev1.Pod) error {
 		logger.Infof("Pod added: %s/%s", pod.Namespace, pod.Name)
 		return nil
 	})
 
 	// Create the controller with custom configuration.
-	cfg := &controller.Config{
-		Name:      "config-custom-controller",
+	cfg := &controller.TypedConfig[*corev1.Pod]{
 		Handler:   hand,
 		Retriever: retr,
-		Logger:    logger,
+		Config: controller.Config{
+			Name:   "config-custom-controller",
+			Logger: logger,
 
-		ProcessingJobRetries: 5,
-		ResyncInterval:       45 * time.Second,
-		ConcurrentWorkers:    1,
+			ProcessingJobRetries: 5,
+			ResyncInterval:       45 * time.Second,
+			ConcurrentWorkers:    1,
+		},
 	}
-	ctrl, err := controller.New(cfg)
+	ctrl, err := controller.NewTyped(cfg)
 	if err != nil {
 		return fmt.Errorf("could not create controller: %w", err)
 	}
@@ -9,19 +9,18 @@ import (
 
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 
 	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/retrieve"
 	"github.com/spotahome/kooper/v2/log"
 	kooperlogrus "github.com/spotahome/kooper/v2/log/logrus"
+	"github.com/spotahome/kooper/v2/manager"
 )
 
 func run() error {
@@ -69,18 +68,9 @@ func run() error {
 
 	// Create the controller for deployments.
 	ctrlDep, err := controller.New(&controller.Config{
-		Name:    "multi-resource-controller-deployments",
-		Handler: hand,
-		Retriever: controller.MustRetrieverFromListerWatcher(
-			&cache.ListWatch{
-				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-					return k8scli.AppsV1().Deployments("").List(context.Background(), options)
-				},
-				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-					return k8scli.AppsV1().Deployments("").Watch(context.Background(), options)
-				},
-			},
-		),
+		Name:                 "multi-resource-controller-deployments",
+		Handler:              hand,
+		Retriever:            retrieve.Deployments(k8scli, retrieve.Options{}),
 		Logger:               logger,
 		ProcessingJobRetries: retries,
 		ResyncInterval:       resyncInterval,
@@ -92,18 +82,9 @@ func run() error {
 
 	// Create the controller for statefulsets.
 	ctrlSt, err := controller.New(&controller.Config{
-		Name:    "multi-resource-controller-statefulsets",
-		Handler: hand,
-		Retriever: controller.MustRetrieverFromListerWatcher(
-			&cache.ListWatch{
-				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-					return k8scli.AppsV1().StatefulSets("").List(context.Background(), options)
-				},
-				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-					return k8scli.AppsV1().StatefulSets("").Watch(context.Background(), options)
-				},
-			},
-		),
+		Name:                 "multi-resource-controller-statefulsets",
+		Handler:              hand,
+		Retriever:            retrieve.StatefulSets(k8scli, retrieve.Options{}),
 		Logger:               logger,
 		ProcessingJobRetries: retries,
 		ResyncInterval:       resyncInterval,
@@ -113,20 +94,27 @@ func run() error {
 		return fmt.Errorf("could not create controller: %w", err)
 	}
 
+	// Register our controllers on the manager so they share the lifecycle, if one fails
+	// all of them will be stopped.
+	mgr, err := manager.New(&manager.Config{
+		Logger: logger,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create manager: %w", err)
+	}
+
+	err = mgr.Add("deployments", ctrlDep)
+	if err != nil {
+		return fmt.Errorf("could not register deployment resource controller: %w", err)
+	}
+
+	err = mgr.Add("statefulsets", ctrlSt)
+	if err != nil {
+		return fmt.Errorf("could not register statefulset resource controller: %w", err)
+	}
+
 	// Start our controllers.
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	errC := make(chan error)
-	go func() {
-		errC <- ctrlDep.Run(ctx)
-	}()
-
-	go func() {
-		errC <- ctrlSt.Run(ctx)
-	}()
-
-	// Wait until one finishes.
-	err = <-errC
+	err = mgr.Run(context.Background())
 	if err != nil {
 		return fmt.Errorf("error running controllers: %w", err)
 	}
@@ -0,0 +1,217 @@
+// Package manager runs multiple controllers with a shared lifecycle.
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/leaderelection"
+	"github.com/spotahome/kooper/v2/log"
+)
+
+// Config is the manager configuration.
+type Config struct {
+	// LeaderElector is optional, if set, all the controllers will run only when the leadership is
+	// acquired, using a single leader election. The controllers should not have their own leader elector.
+	LeaderElector leaderelection.Runner
+	// Logger will log messages of the manager.
+	Logger log.Logger
+	// Degrade will keep the rest of the controllers running when one of them fails, by default
+	// all the controllers are stopped when one fails (fail-fast). The failed controllers will
+	// make the manager not ready.
+	Degrade bool
+}
+
+func (c *Config) setDefaults() {
+	if c.Logger == nil {
+		c.Logger = log.NewStd(false)
+		c.Logger.Warningf("no logger specified, fallback to default logger, to disable logging use a explicit Noop logger")
+	}
+	c.Logger = c.Logger.WithKV(log.KV{"service": "kooper.manager"})
+}
+
+type namedController struct {
+	name string
+	ctrl controller.Controller
+}
+
+// Manager runs multiple controllers under the same context, leader election and health checks.
+type Manager struct {
+	cfg    Config
+	logger log.Logger
+
+	mu          sync.Mutex
+	controllers []namedController
+	failed      map[string]error
+	running     bool
+	leading     bool
+}
+
+// New returns a new manager.
+func New(cfg *Config) (*Manager, error) {
+	cfg.setDefaults()
+
+	return &Manager{
+		cfg:    *cfg,
+		logger: cfg.Logger,
+		failed: map[string]error{},
+	}, nil
+}
+
+// Add registers a controller on the manager, the controllers can't be added once the manager is running.
+func (m *Manager) Add(name string, ctrl controller.Controller) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return fmt.Errorf("can't add controllers to a running manager")
+	}
+
+	if ctrl == nil {
+		return fmt.Errorf("controller %q is nil", name)
+	}
+
+	for _, c := range m.controllers {
+		if c.name == name {
+			return fmt.Errorf("controller %q already registered", name)
+		}
+	}
+
+	m.controllers = append(m.controllers, namedController{name: name, ctrl: ctrl})
+
+	return nil
+}
+
+// Run runs all the registered controllers and blocks until the context is `Done` or (when not degrading)
+// one of the controllers fails, in that case all the controllers are stopped and the error is returned.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return fmt.Errorf("manager already running")
+	}
+	if len(m.controllers) == 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("at least one controller is required")
+	}
+	m.running = true
+	m.failed = map[string]error{}
+	ctrls := append([]namedController{}, m.controllers...)
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		m.running = false
+		m.mu.Unlock()
+	}()
+
+	if m.cfg.LeaderElector != nil {
+		return m.cfg.LeaderElector.Run(ctx, func(ctx context.Context) error {
+			m.setLeading(true)
+			defer m.setLeading(false)
+			return m.run(ctx, ctrls)
+		})
+	}
+
+	return m.run(ctx, ctrls)
+}
+
+func (m *Manager) run(ctx context.Context, ctrls []namedController) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errC := make(chan error, len(ctrls))
+	for _, c := range ctrls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			m.logger.Infof("starting controller %q", c.name)
+			err := c.ctrl.Run(ctx)
+			if err == nil {
+				m.logger.Infof("controller %q stopped", c.name)
+				return
+			}
+
+			err = fmt.Errorf("controller %q failed: %w", c.name, err)
+			m.logger.Errorf("%s", err)
+			m.setFailed(c.name, err)
+			errC <- err
+
+			if !m.cfg.Degrade {
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errC)
+
+	errs := []error{}
+	for err := range errC {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) setLeading(leading bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leading = leading
+}
+
+func (m *Manager) setFailed(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed[name] = err
+}
+
+// Health satisfies controller.HealthChecker interface, it aggregates the health of all the controllers.
+func (m *Manager) Health(ctx context.Context) error {
+	m.mu.Lock()
+	ctrls := append([]namedController{}, m.controllers...)
+	m.mu.Unlock()
+
+	errs := []error{}
+	for _, c := range ctrls {
+		if err := c.ctrl.Health(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Ready satisfies controller.HealthChecker interface, it aggregates the readiness of all the controllers
+// and the failed controllers. A manager waiting to acquire the leadership is ready.
+func (m *Manager) Ready(ctx context.Context) error {
+	m.mu.Lock()
+	// The controllers don't run until the leadership is acquired.
+	if m.running && m.cfg.LeaderElector != nil && !m.leading {
+		m.mu.Unlock()
+		return nil
+	}
+	ctrls := append([]namedController{}, m.controllers...)
+	errs := []error{}
+	for _, c := range ctrls {
+		if err, ok := m.failed[c.name]; ok {
+			errs = append(errs, fmt.Errorf("%w: %w", controller.ErrNotReady, err))
+		}
+	}
+	m.mu.Unlock()
+
+	for _, c := range ctrls {
+		if err := c.ctrl.Ready(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+var _ controller.HealthChecker = &Manager{}
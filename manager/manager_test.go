@@ -0,0 +1,249 @@
+package manager_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/log"
+	"github.com/spotahome/kooper/v2/manager"
+)
+
+// testController is a controller that will fail after the configured duration or
+// block until the context is done.
+type testController struct {
+	failAfter time.Duration
+	err       error
+	healthErr error
+	readyErr  error
+	stopped   chan struct{}
+}
+
+func newTestController(failAfter time.Duration, err error) *testController {
+	return &testController{failAfter: failAfter, err: err, stopped: make(chan struct{})}
+}
+
+func (t *testController) Run(ctx context.Context) error {
+	defer close(t.stopped)
+
+	if t.err == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(t.failAfter):
+		return t.err
+	}
+}
+
+func (t *testController) Health(context.Context) error { return t.healthErr }
+func (t *testController) Ready(context.Context) error  { return t.readyErr }
+
+// testLeaderElector is a leader election runner that will lead or wait forever for the leadership.
+type testLeaderElector struct {
+	lead bool
+}
+
+func (t testLeaderElector) Run(ctx context.Context, f func(context.Context) error) error {
+	if t.lead {
+		return f(ctx)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (t testLeaderElector) Leader() string { return "" }
+func (t testLeaderElector) IsLeader() bool { return t.lead }
+
+func TestManagerAdd(t *testing.T) {
+	tests := map[string]struct {
+		names  []string
+		expErr bool
+	}{
+		"Registering controllers with different names should not fail.": {
+			names: []string{"c1", "c2", "c3"},
+		},
+
+		"Registering controllers with the same name should fail.": {
+			names:  []string{"c1", "c2", "c1"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mgr, err := manager.New(&manager.Config{Logger: log.Dummy})
+			require.NoError(t, err)
+
+			var gotErr error
+			for _, n := range test.names {
+				if err := mgr.Add(n, newTestController(0, nil)); err != nil {
+					gotErr = err
+				}
+			}
+
+			if test.expErr {
+				assert.Error(t, gotErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestManagerRun(t *testing.T) {
+	tests := map[string]struct {
+		degrade       bool
+		expOthersStop bool
+	}{
+		"A failing controller in fail-fast mode should stop all the controllers.": {
+			degrade:       false,
+			expOthersStop: true,
+		},
+
+		"A failing controller in degrade mode should keep the rest of the controllers running.": {
+			degrade:       true,
+			expOthersStop: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			mgr, err := manager.New(&manager.Config{Logger: log.Dummy, Degrade: test.degrade})
+			require.NoError(err)
+
+			failing := newTestController(10*time.Millisecond, errors.New("wanted error"))
+			other := newTestController(0, nil)
+			require.NoError(mgr.Add("failing", failing))
+			require.NoError(mgr.Add("other", other))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			errC := make(chan error, 1)
+			go func() { errC <- mgr.Run(ctx) }()
+
+			// Wait until the failing controller has finished.
+			<-failing.stopped
+			assert.ErrorIs(mgr.Ready(context.Background()), controller.ErrNotReady)
+
+			select {
+			case <-other.stopped:
+				assert.True(test.expOthersStop, "the other controller shouldn't be stopped")
+			case <-time.After(100 * time.Millisecond):
+				assert.False(test.expOthersStop, "the other controller should be stopped")
+			}
+
+			// Stop the manager.
+			cancel()
+			select {
+			case err := <-errC:
+				assert.Error(err)
+			case <-time.After(1 * time.Second):
+				assert.Fail("manager should be stopped")
+			}
+		})
+	}
+}
+
+func TestManagerHealth(t *testing.T) {
+	tests := map[string]struct {
+		controllers []*testController
+		expHealth   bool
+		expReady    bool
+	}{
+		"All healthy and ready controllers should make the manager healthy and ready.": {
+			controllers: []*testController{{}, {}},
+			expHealth:   true,
+			expReady:    true,
+		},
+
+		"A not healthy controller should make the manager not healthy.": {
+			controllers: []*testController{{}, {healthErr: controller.ErrNotHealthy}},
+			expHealth:   false,
+			expReady:    true,
+		},
+
+		"A not ready controller should make the manager not ready.": {
+			controllers: []*testController{{readyErr: controller.ErrNotReady}, {}},
+			expHealth:   true,
+			expReady:    false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			mgr, err := manager.New(&manager.Config{Logger: log.Dummy})
+			require.NoError(err)
+
+			for i, c := range test.controllers {
+				require.NoError(mgr.Add(string(rune('a'+i)), c))
+			}
+
+			assert.Equal(test.expHealth, mgr.Health(context.Background()) == nil)
+			assert.Equal(test.expReady, mgr.Ready(context.Background()) == nil)
+		})
+	}
+}
+
+func TestManagerReadyWithLeaderElection(t *testing.T) {
+	tests := map[string]struct {
+		lead     bool
+		expReady bool
+	}{
+		"A manager waiting for the leadership should be ready.": {
+			lead:     false,
+			expReady: true,
+		},
+
+		"A leading manager should have the readiness of its controllers.": {
+			lead:     true,
+			expReady: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mgr, err := manager.New(&manager.Config{
+				LeaderElector: testLeaderElector{lead: test.lead},
+				Logger:        log.Dummy,
+			})
+			require.NoError(err)
+
+			// The controller is not ready until it runs (like the not running controllers).
+			c := newTestController(0, nil)
+			c.readyErr = controller.ErrNotReady
+			require.NoError(mgr.Add("c1", c))
+			assert.ErrorIs(mgr.Ready(ctx), controller.ErrNotReady)
+
+			resultC := make(chan error)
+			go func() { resultC <- mgr.Run(ctx) }()
+
+			assert.Eventually(func() bool {
+				return test.expReady == (mgr.Ready(ctx) == nil)
+			}, time.Second, 10*time.Millisecond)
+
+			cancel()
+			require.NoError(<-resultC)
+		})
+	}
+}
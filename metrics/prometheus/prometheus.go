@@ -8,11 +8,13 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spotahome/kooper/v2/controller"
+	"github.com/spotahome/kooper/v2/controller/leaderelection"
 )
 
 const (
 	promNamespace           = "kooper"
 	promControllerSubsystem = "controller"
+	promLESubsystem         = "leader_election"
 )
 
 // Config is the Recorder Config.
@@ -26,6 +28,12 @@ type Config struct {
 	// ProcessingBuckets sets custom buckets for the duration/latency processing metrics.
 	// Check https://godoc.org/github.com/prometheus/client_golang/prometheus#pkg-variables
 	ProcessingBuckets []float64
+	// RetrieverBuckets sets custom buckets for the duration/latency retriever operations metrics.
+	// Check https://godoc.org/github.com/prometheus/client_golang/prometheus#pkg-variables
+	RetrieverBuckets []float64
+	// LeaderElectionRenewBuckets sets custom buckets for the duration/latency of the leader election renewals.
+	// Check https://godoc.org/github.com/prometheus/client_golang/prometheus#pkg-variables
+	LeaderElectionRenewBuckets []float64
 }
 
 func (c *Config) defaults() {
@@ -42,6 +50,14 @@ func (c *Config) defaults() {
 	if len(c.ProcessingBuckets) == 0 {
 		c.ProcessingBuckets = prometheus.DefBuckets
 	}
+
+	if len(c.RetrieverBuckets) == 0 {
+		c.RetrieverBuckets = prometheus.DefBuckets
+	}
+
+	if len(c.LeaderElectionRenewBuckets) == 0 {
+		c.LeaderElectionRenewBuckets = prometheus.DefBuckets
+	}
 }
 
 // Recorder implements the metrics recording in a prometheus registry.
@@ -49,8 +65,15 @@ type Recorder struct {
 	reg prometheus.Registerer
 
 	queuedEventsTotal      *prometheus.CounterVec
+	filteredEventsTotal    *prometheus.CounterVec
 	inQueueEventDuration   *prometheus.HistogramVec
 	processedEventDuration *prometheus.HistogramVec
+	processingTimeouts     *prometheus.CounterVec
+	processingPanics       *prometheus.CounterVec
+	retrieverOpDuration    *prometheus.HistogramVec
+	leIsLeader             *prometheus.GaugeVec
+	leTransitionsTotal     *prometheus.CounterVec
+	leRenewDuration        *prometheus.HistogramVec
 }
 
 // New returns a new Prometheus implementation for a metrics recorder.
@@ -67,6 +90,13 @@ func New(cfg Config) *Recorder {
 			Help:      "Total number of events queued.",
 		}, []string{"controller", "requeue"}),
 
+		filteredEventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promControllerSubsystem,
+			Name:      "filtered_events_total",
+			Help:      "Total number of events filtered by the predicates.",
+		}, []string{"controller"}),
+
 		inQueueEventDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Namespace: promNamespace,
 			Subsystem: promControllerSubsystem,
@@ -82,13 +112,64 @@ func New(cfg Config) *Recorder {
 			Help:      "The duration for an event to be processed.",
 			Buckets:   cfg.ProcessingBuckets,
 		}, []string{"controller", "success"}),
+
+		processingTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promControllerSubsystem,
+			Name:      "processing_timeouts_total",
+			Help:      "Total number of event processings that reached the timeout.",
+		}, []string{"controller"}),
+
+		processingPanics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promControllerSubsystem,
+			Name:      "processing_panics_total",
+			Help:      "Total number of event processings that panicked.",
+		}, []string{"controller"}),
+
+		retrieverOpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: promNamespace,
+			Subsystem: promControllerSubsystem,
+			Name:      "retriever_operation_duration_seconds",
+			Help:      "The duration of the retriever operations (list and watch).",
+			Buckets:   cfg.RetrieverBuckets,
+		}, []string{"retriever", "operation", "success"}),
+
+		leIsLeader: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: promNamespace,
+			Subsystem: promLESubsystem,
+			Name:      "is_leader",
+			Help:      "Is the instance the leader of the lock.",
+		}, []string{"lock"}),
+
+		leTransitionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: promNamespace,
+			Subsystem: promLESubsystem,
+			Name:      "transitions_total",
+			Help:      "Total number of leadership transitions observed.",
+		}, []string{"lock"}),
+
+		leRenewDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: promNamespace,
+			Subsystem: promLESubsystem,
+			Name:      "renew_duration_seconds",
+			Help:      "The duration of the lock acquisitions and renewals.",
+			Buckets:   cfg.LeaderElectionRenewBuckets,
+		}, []string{"lock", "success"}),
 	}
 
 	// Register metrics.
 	r.reg.MustRegister(
 		r.queuedEventsTotal,
+		r.filteredEventsTotal,
 		r.inQueueEventDuration,
-		r.processedEventDuration)
+		r.processedEventDuration,
+		r.processingTimeouts,
+		r.processingPanics,
+		r.retrieverOpDuration,
+		r.leIsLeader,
+		r.leTransitionsTotal,
+		r.leRenewDuration)
 
 	return r
 }
@@ -98,6 +179,11 @@ func (r Recorder) IncResourceEventQueued(ctx context.Context, controller string,
 	r.queuedEventsTotal.WithLabelValues(controller, strconv.FormatBool(isRequeue)).Inc()
 }
 
+// IncResourceEventFiltered satisfies controller.MetricsRecorder interface.
+func (r Recorder) IncResourceEventFiltered(ctx context.Context, controller string) {
+	r.filteredEventsTotal.WithLabelValues(controller).Inc()
+}
+
 // ObserveResourceInQueueDuration satisfies controller.MetricsRecorder interface.
 func (r Recorder) ObserveResourceInQueueDuration(ctx context.Context, controller string, queuedAt time.Time) {
 	r.inQueueEventDuration.WithLabelValues(controller).
@@ -110,6 +196,22 @@ func (r Recorder) ObserveResourceProcessingDuration(ctx context.Context, control
 		Observe(time.Since(startProcessingAt).Seconds())
 }
 
+// IncResourceProcessingTimeout satisfies controller.MetricsRecorder interface.
+func (r Recorder) IncResourceProcessingTimeout(ctx context.Context, controller string) {
+	r.processingTimeouts.WithLabelValues(controller).Inc()
+}
+
+// IncResourceProcessingPanic satisfies controller.MetricsRecorder interface.
+func (r Recorder) IncResourceProcessingPanic(ctx context.Context, controller string) {
+	r.processingPanics.WithLabelValues(controller).Inc()
+}
+
+// ObserveRetrieverOperationDuration satisfies controller.MetricsRecorder interface.
+func (r Recorder) ObserveRetrieverOperationDuration(ctx context.Context, retriever string, operation string, success bool, startAt time.Time) {
+	r.retrieverOpDuration.WithLabelValues(retriever, operation, strconv.FormatBool(success)).
+		Observe(time.Since(startAt).Seconds())
+}
+
 // RegisterResourceQueueLengthFunc satisfies controller.MetricsRecorder interface.
 func (r Recorder) RegisterResourceQueueLengthFunc(controller string, f func(context.Context) int) error {
 	err := r.reg.Register(prometheus.NewGaugeFunc(
@@ -129,5 +231,26 @@ func (r Recorder) RegisterResourceQueueLengthFunc(controller string, f func(cont
 	return nil
 }
 
+// SetLeader satisfies leaderelection.MetricsRecorder interface.
+func (r Recorder) SetLeader(ctx context.Context, lock string, isLeader bool) {
+	v := 0.0
+	if isLeader {
+		v = 1
+	}
+	r.leIsLeader.WithLabelValues(lock).Set(v)
+}
+
+// IncLeaderTransitions satisfies leaderelection.MetricsRecorder interface.
+func (r Recorder) IncLeaderTransitions(ctx context.Context, lock string) {
+	r.leTransitionsTotal.WithLabelValues(lock).Inc()
+}
+
+// ObserveLeaderRenewDuration satisfies leaderelection.MetricsRecorder interface.
+func (r Recorder) ObserveLeaderRenewDuration(ctx context.Context, lock string, success bool, startAt time.Time) {
+	r.leRenewDuration.WithLabelValues(lock, strconv.FormatBool(success)).
+		Observe(time.Since(startAt).Seconds())
+}
+
 // Check interfaces implementation.
 var _ controller.MetricsRecorder = &Recorder{}
+var _ leaderelection.MetricsRecorder = &Recorder{}
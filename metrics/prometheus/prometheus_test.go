@@ -199,6 +199,140 @@ func TestPrometheusRecorder(t *testing.T) {
 			},
 		},
 
+		"Incrementing the filtered events should record the metrics.": {
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				r.IncResourceEventFiltered(ctx, "ctrl1")
+				r.IncResourceEventFiltered(ctx, "ctrl1")
+				r.IncResourceEventFiltered(ctx, "ctrl2")
+			},
+			expMetrics: []string{
+				`# HELP kooper_controller_filtered_events_total Total number of events filtered by the predicates.`,
+				`# TYPE kooper_controller_filtered_events_total counter`,
+
+				`kooper_controller_filtered_events_total{controller="ctrl1"} 2`,
+				`kooper_controller_filtered_events_total{controller="ctrl2"} 1`,
+			},
+		},
+
+		"Observing the duration of retriever operations should record the metrics.": {
+			cfg: kooperprometheus.Config{
+				RetrieverBuckets: []float64{1, 5},
+			},
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				t0 := time.Now()
+				r.ObserveRetrieverOperationDuration(ctx, "ret1", "list", true, t0.Add(-3*time.Second))
+				r.ObserveRetrieverOperationDuration(ctx, "ret1", "list", true, t0.Add(-280*time.Millisecond))
+				r.ObserveRetrieverOperationDuration(ctx, "ret1", "watch", false, t0.Add(-7*time.Second))
+			},
+			expMetrics: []string{
+				`# HELP kooper_controller_retriever_operation_duration_seconds The duration of the retriever operations (list and watch).`,
+				`# TYPE kooper_controller_retriever_operation_duration_seconds histogram`,
+
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="list",retriever="ret1",success="true",le="1"} 1`,
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="list",retriever="ret1",success="true",le="5"} 2`,
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="list",retriever="ret1",success="true",le="+Inf"} 2`,
+				`kooper_controller_retriever_operation_duration_seconds_count{operation="list",retriever="ret1",success="true"} 2`,
+
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="watch",retriever="ret1",success="false",le="1"} 0`,
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="watch",retriever="ret1",success="false",le="5"} 0`,
+				`kooper_controller_retriever_operation_duration_seconds_bucket{operation="watch",retriever="ret1",success="false",le="+Inf"} 1`,
+				`kooper_controller_retriever_operation_duration_seconds_count{operation="watch",retriever="ret1",success="false"} 1`,
+			},
+		},
+
+		"Incrementing the processing timeouts should record the metrics.": {
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				r.IncResourceProcessingTimeout(ctx, "ctrl1")
+				r.IncResourceProcessingTimeout(ctx, "ctrl1")
+				r.IncResourceProcessingTimeout(ctx, "ctrl2")
+			},
+			expMetrics: []string{
+				`# HELP kooper_controller_processing_timeouts_total Total number of event processings that reached the timeout.`,
+				`# TYPE kooper_controller_processing_timeouts_total counter`,
+
+				`kooper_controller_processing_timeouts_total{controller="ctrl1"} 2`,
+				`kooper_controller_processing_timeouts_total{controller="ctrl2"} 1`,
+			},
+		},
+
+		"Incrementing the processing panics should record the metrics.": {
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				r.IncResourceProcessingPanic(ctx, "ctrl1")
+				r.IncResourceProcessingPanic(ctx, "ctrl2")
+				r.IncResourceProcessingPanic(ctx, "ctrl2")
+			},
+			expMetrics: []string{
+				`# HELP kooper_controller_processing_panics_total Total number of event processings that panicked.`,
+				`# TYPE kooper_controller_processing_panics_total counter`,
+
+				`kooper_controller_processing_panics_total{controller="ctrl1"} 1`,
+				`kooper_controller_processing_panics_total{controller="ctrl2"} 2`,
+			},
+		},
+
+		"Setting the leader election state should record the metrics.": {
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				r.SetLeader(ctx, "ns1/lock1", true)
+				r.SetLeader(ctx, "ns1/lock2", true)
+				r.SetLeader(ctx, "ns1/lock2", false)
+			},
+			expMetrics: []string{
+				`# HELP kooper_leader_election_is_leader Is the instance the leader of the lock.`,
+				`# TYPE kooper_leader_election_is_leader gauge`,
+
+				`kooper_leader_election_is_leader{lock="ns1/lock1"} 1`,
+				`kooper_leader_election_is_leader{lock="ns1/lock2"} 0`,
+			},
+		},
+
+		"Incrementing the leader election transitions should record the metrics.": {
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				r.IncLeaderTransitions(ctx, "ns1/lock1")
+				r.IncLeaderTransitions(ctx, "ns1/lock1")
+				r.IncLeaderTransitions(ctx, "ns1/lock2")
+			},
+			expMetrics: []string{
+				`# HELP kooper_leader_election_transitions_total Total number of leadership transitions observed.`,
+				`# TYPE kooper_leader_election_transitions_total counter`,
+
+				`kooper_leader_election_transitions_total{lock="ns1/lock1"} 2`,
+				`kooper_leader_election_transitions_total{lock="ns1/lock2"} 1`,
+			},
+		},
+
+		"Observing the leader election renew duration should record the metrics.": {
+			cfg: kooperprometheus.Config{
+				LeaderElectionRenewBuckets: []float64{0.1, 1},
+			},
+			addMetrics: func(r *kooperprometheus.Recorder) {
+				ctx := context.TODO()
+				t0 := time.Now()
+				r.ObserveLeaderRenewDuration(ctx, "ns1/lock1", true, t0.Add(-50*time.Millisecond))
+				r.ObserveLeaderRenewDuration(ctx, "ns1/lock1", true, t0.Add(-500*time.Millisecond))
+				r.ObserveLeaderRenewDuration(ctx, "ns1/lock1", false, t0.Add(-2*time.Second))
+			},
+			expMetrics: []string{
+				`# HELP kooper_leader_election_renew_duration_seconds The duration of the lock acquisitions and renewals.`,
+				`# TYPE kooper_leader_election_renew_duration_seconds histogram`,
+
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="true",le="0.1"} 1`,
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="true",le="1"} 2`,
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="true",le="+Inf"} 2`,
+				`kooper_leader_election_renew_duration_seconds_count{lock="ns1/lock1",success="true"} 2`,
+
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="false",le="0.1"} 0`,
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="false",le="1"} 0`,
+				`kooper_leader_election_renew_duration_seconds_bucket{lock="ns1/lock1",success="false",le="+Inf"} 1`,
+				`kooper_leader_election_renew_duration_seconds_count{lock="ns1/lock1",success="false"} 1`,
+			},
+		},
+
 		"Registering resource queue length function should measure the size of the queue.": {
 			cfg: kooperprometheus.Config{},
 			addMetrics: func(r *kooperprometheus.Recorder) {